@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"example.net/vulkan-tutorial/golden"
+	"example.net/vulkan-tutorial/renderer"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func init() {
+	runtime.LockOSThread()
+}
+
+// The pipeline cache goes in the user's cache directory, if they have one.
+func defaultPipelineCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vulkangotutorial")
+}
+
+// Surface format presets for -surface-format. Each falls back to sRGB.
+var surfaceFormatPresets = map[string][]vk.SurfaceFormat{
+	"srgb":  renderer.SurfaceFormatsSRGB,
+	"unorm": renderer.SurfaceFormatsUNORM,
+	"10bit": renderer.SurfaceFormats10Bit,
+	"hdr10": renderer.SurfaceFormatsHDR10,
+	"scrgb": renderer.SurfaceFormatsScRGB,
+}
+
+// Depth buffer presets for -depth.
+var depthPresets = map[string][]vk.Format{
+	"none":          nil,
+	"depth":         renderer.DepthFormats,
+	"depth-stencil": renderer.DepthStencilFormats,
+}
+
+// Hooks for the windowed triangle. V cycles through the present policies
+// and F through the number of frames in flight.
+type triangleHooks struct {
+	renderer.TriangleHooks
+}
+
+func (triangleHooks) OnSetup(app *renderer.TriangleApplication) error {
+	// Headless applications don't have keys.
+	if app.Window() == nil {
+		return nil
+	}
+	app.Window().SetKeyCallback(func(_ *glfw.Window, key glfw.Key, _ int, action glfw.Action, _ glfw.ModifierKey) {
+		if key == glfw.KeyV && action == glfw.Press {
+			app.SetPresentPolicy(app.PresentPolicy.Next())
+			fmt.Printf("Present policy: %s\n", app.PresentPolicy)
+		}
+		if key == glfw.KeyF && action == glfw.Press {
+			frames := app.FramesInFlight%uint(len(app.Swapchain().Images)) + 1
+			if err := app.SetFramesInFlight(frames); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return
+			}
+			fmt.Printf("Frames in flight: %d\n", app.FramesInFlight)
+		}
+	})
+	return nil
+}
+
+func main() {
+	headless := flag.String("headless", "", "render offscreen and write the last frame to this PNG file")
+	frames := flag.Int("frames", 1, "number of frames to render in headless mode")
+	validation := flag.Bool("validation", true, "enable the Khronos validation layer")
+	goldenFile := flag.String("golden", "", "render offscreen and compare against this golden PNG file")
+	tolerance := flag.Uint("tolerance", 2, "largest per channel difference allowed by -golden")
+	update := flag.Bool("update", false, "write the render to the -golden file instead of comparing")
+	pipelines := flag.String("pipelines", "", "load the pipeline descriptions from this JSON or YAML file")
+	pipelineCache := flag.String("pipeline-cache", defaultPipelineCacheDir(), "directory for the on-disk pipeline cache, empty to keep it in memory")
+	surfaceFormat := flag.String("surface-format", "srgb", "preferred swapchain format: srgb, unorm, 10bit, hdr10 or scrgb")
+	present := flag.String("present", "low-latency", "present policy: low-latency, vsync, uncapped or fifo-relaxed, V cycles them while running")
+	framesInFlight := flag.Uint("frames-in-flight", renderer.DefaultFramesInFlight, "frames recorded ahead of the GPU, F cycles them while running")
+	images := flag.Uint("images", 0, "swapchain images to request, 0 for one more than the surface's minimum")
+	depth := flag.String("depth", "none", "depth buffer: none, depth or depth-stencil")
+	samples := flag.Uint("samples", 1, "MSAA samples per pixel, clamped to what the device supports")
+	mesh := flag.Bool("mesh", false, "draw a quad from vertex and index buffers, with a pipeline built from the vertex struct unless -pipelines is given")
+	spin := flag.Bool("spin", false, "spin the -mesh quad with a transform written to a uniform ring every frame")
+	flag.Parse()
+
+	app := renderer.TriangleApplication{
+		RequiredInstanceExtensionNames: []string{},
+		RequiredInstanceLayerNames:     []string{},
+		SelectPhysicalDeviceIndex:      renderer.DefaultSelectPhysicalDeviceIndex,
+		RequiredDeviceLayerNames:       []string{},
+		RequiredDeviceExtensionNames: []string{
+			"VK_KHR_portability_subset",
+			vk.KhrSwapchainExtensionName,
+		},
+		VertexShaderFile:    "shaders/vert.spv",
+		FragmentShaderFile:  "shaders/frag.spv",
+		FramesInFlight:      *framesInFlight,
+		SwapchainImageCount: uint32(*images),
+		Samples:             vk.SampleCountFlagBits(*samples),
+		PipelineCacheDir:    *pipelineCache,
+	}
+	if *mesh || *spin {
+		var desc renderer.GraphicsPipelineDesc
+		var err error
+		if *spin {
+			app.Hooks = &spinHooks{}
+			desc, err = meshPipelineDesc("spin", "shaders/spinvert.spv")
+			desc = desc.WithDynamicUniformBuffer(0, 0)
+		} else {
+			app.Hooks = &meshHooks{}
+			desc, err = meshPipelineDesc("mesh", "shaders/meshvert.spv")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		app.PipelineDescs = []renderer.GraphicsPipelineDesc{desc}
+	}
+	if *pipelines != "" {
+		descs, err := renderer.LoadPipelineDescs(*pipelines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		app.PipelineDescs = descs
+	}
+	if preset, ok := surfaceFormatPresets[*surfaceFormat]; ok {
+		app.SurfaceFormats = append(append([]vk.SurfaceFormat{}, preset...), renderer.SurfaceFormatsSRGB...)
+	} else {
+		fmt.Fprintf(os.Stderr, "unknown -surface-format %q\n", *surfaceFormat)
+		os.Exit(2)
+	}
+	if formats, ok := depthPresets[*depth]; ok {
+		app.DepthFormats = formats
+	} else {
+		fmt.Fprintf(os.Stderr, "unknown -depth %q\n", *depth)
+		os.Exit(2)
+	}
+	if policy, err := renderer.ParsePresentPolicy(*present); err == nil {
+		app.PresentPolicy = policy
+	} else {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	if *validation {
+		app.RequiredInstanceLayerNames = append(app.RequiredInstanceLayerNames, "VK_LAYER_KHRONOS_validation")
+		app.RequiredDeviceLayerNames = append(app.RequiredDeviceLayerNames, "VK_LAYER_KHRONOS_validation")
+	}
+
+	// Compare an offscreen render with the golden image.
+	if *goldenFile != "" {
+		app.RequiredDeviceExtensionNames = []string{}
+		err := golden.Run(&app, *goldenFile, golden.Options{
+			Frames:    *frames,
+			Tolerance: uint8(*tolerance),
+			Update:    *update,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Render offscreen, without needing a display.
+	if *headless != "" {
+		// No swapchain, so none of the device extensions are needed.
+		app.RequiredDeviceExtensionNames = []string{}
+		img, err := app.RenderHeadless(*frames)
+		if err == nil {
+			err = renderer.SavePNG(*headless, img)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if app.Hooks == nil {
+		app.Hooks = triangleHooks{}
+	}
+	if err := app.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"example.net/vulkan-tutorial/renderer"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// A vertex of the mesh example, with the locations of shaders/mesh.vert.
+type meshVertex struct {
+	Position [2]float32 `vk:"location=0"`
+	Color    [3]float32 `vk:"location=1"`
+}
+
+// A pipeline drawing meshVertex vertices with the vertex shader, with the
+// vertex input taken from meshVertex.
+func meshPipelineDesc(name, vertexFile string) (renderer.GraphicsPipelineDesc, error) {
+	binding, attributes, err := renderer.VertexLayout(meshVertex{}, 0, vk.VertexInputRateVertex)
+	if err != nil {
+		return renderer.GraphicsPipelineDesc{}, err
+	}
+	return renderer.NewGraphicsPipelineDesc().
+		WithName(name).
+		WithShaders(vertexFile, "shaders/frag.spv").
+		WithVertexInput([]vk.VertexInputBindingDescription{binding}, attributes), nil
+}
+
+// A quad made of two indexed triangles, with clockwise front faces.
+var (
+	quadVertices = []meshVertex{
+		{Position: [2]float32{-0.5, -0.5}, Color: [3]float32{1.0, 0.0, 0.0}},
+		{Position: [2]float32{0.5, -0.5}, Color: [3]float32{0.0, 1.0, 0.0}},
+		{Position: [2]float32{0.5, 0.5}, Color: [3]float32{0.0, 0.0, 1.0}},
+		{Position: [2]float32{-0.5, 0.5}, Color: [3]float32{1.0, 1.0, 1.0}},
+	}
+	quadIndices = []uint16{0, 1, 2, 2, 3, 0}
+)
+
+// Hooks that draw the quad from vertex and index buffers instead of the
+// triangle from the shader.
+type meshHooks struct {
+	triangleHooks
+	mesh renderer.Mesh
+}
+
+func (hooks *meshHooks) OnSetup(app *renderer.TriangleApplication) error {
+	if err := hooks.triangleHooks.OnSetup(app); err != nil {
+		return err
+	}
+
+	// Upload the quad.
+	var err error
+	hooks.mesh.Vertices, err = renderer.NewVertexBuffer(app, quadVertices)
+	if err != nil {
+		return err
+	}
+	hooks.mesh.Indices, err = renderer.NewIndexBuffer16(app, quadIndices)
+	if err != nil {
+		// OnCleanup isn't called when OnSetup fails.
+		hooks.OnCleanup()
+		return err
+	}
+	return nil
+}
+
+func (hooks *meshHooks) OnRecord(cmd vk.CommandBuffer, imageIndex uint32) error {
+	hooks.mesh.Draw(cmd, 1)
+	return nil
+}
+
+func (hooks *meshHooks) OnCleanup() {
+	hooks.mesh.Cleanup()
+	hooks.mesh = renderer.Mesh{}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"example.net/vulkan-tutorial/renderer"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// The uniform block of shaders/spin.vert.
+type spinUniforms struct {
+	Transform [4][4]float32
+	Time      float32
+}
+
+// Hooks that spin the quad, writing its transform to a uniform ring every
+// frame.
+type spinHooks struct {
+	meshHooks
+	app    *renderer.TriangleApplication
+	layout *renderer.BlockLayout
+	ring   *renderer.UniformRing
+	pool   vk.DescriptorPool
+	set    vk.DescriptorSet
+	time   time.Duration
+}
+
+func (hooks *spinHooks) OnSetup(app *renderer.TriangleApplication) (err error) {
+	if err := hooks.meshHooks.OnSetup(app); err != nil {
+		return err
+	}
+	hooks.app = app
+
+	// OnCleanup isn't called when OnSetup fails, so destroy what was
+	// created.
+	defer func() {
+		if err != nil {
+			hooks.OnCleanup()
+		}
+	}()
+
+	// Lay out the block the shader declares.
+	pipeline := app.Pipeline()
+	if len(pipeline.DescriptorBindings) != 1 || pipeline.DescriptorBindings[0].Set != 0 {
+		return fmt.Errorf("the spin pipeline should have one uniform block in set 0, it has %d descriptor bindings",
+			len(pipeline.DescriptorBindings))
+	}
+	binding := pipeline.DescriptorBindings[0]
+	hooks.layout, err = renderer.BindingBlockLayout(binding, spinUniforms{})
+	if err != nil {
+		return err
+	}
+
+	// One block per frame.
+	hooks.ring, err = renderer.NewUniformRing(app, vk.DeviceSize(hooks.layout.Size))
+	if err != nil {
+		return err
+	}
+
+	// Create the descriptor pool.
+	poolInfo := vk.DescriptorPoolCreateInfo{
+		SType:         vk.StructureTypeDescriptorPoolCreateInfo,
+		MaxSets:       1,
+		PoolSizeCount: 1,
+		PPoolSizes: []vk.DescriptorPoolSize{
+			vk.DescriptorPoolSize{
+				Type:            binding.DescriptorType,
+				DescriptorCount: 1,
+			},
+		},
+	}
+	err = renderer.CheckResult("vkCreateDescriptorPool", vk.CreateDescriptorPool(app.Device(), &poolInfo, nil, &hooks.pool))
+	if err != nil {
+		return err
+	}
+
+	// Allocate the descriptor set.
+	allocInfo := vk.DescriptorSetAllocateInfo{
+		SType:              vk.StructureTypeDescriptorSetAllocateInfo,
+		DescriptorPool:     hooks.pool,
+		DescriptorSetCount: 1,
+		PSetLayouts:        pipeline.DescriptorSetLayouts[:1],
+	}
+	err = renderer.CheckResult("vkAllocateDescriptorSets", vk.AllocateDescriptorSets(app.Device(), &allocInfo, &hooks.set))
+	if err != nil {
+		return err
+	}
+
+	// Point it at the ring.
+	vk.UpdateDescriptorSets(app.Device(), 1, []vk.WriteDescriptorSet{
+		vk.WriteDescriptorSet{
+			SType:           vk.StructureTypeWriteDescriptorSet,
+			DstSet:          hooks.set,
+			DstBinding:      binding.Binding,
+			DescriptorCount: 1,
+			DescriptorType:  binding.DescriptorType,
+			PBufferInfo: []vk.DescriptorBufferInfo{
+				hooks.ring.DescriptorBufferInfo(vk.DeviceSize(hooks.layout.Size)),
+			},
+		},
+	}, 0, nil)
+	return nil
+}
+
+func (hooks *spinHooks) OnUpdate(dt time.Duration) error {
+	hooks.time += dt
+	return nil
+}
+
+func (hooks *spinHooks) OnRecord(cmd vk.CommandBuffer, imageIndex uint32) error {
+	// Write this frame's transform.
+	seconds := hooks.time.Seconds()
+	s, c := float32(math.Sin(seconds)), float32(math.Cos(seconds))
+	offset, err := hooks.ring.WriteBlock(hooks.layout, spinUniforms{
+		Transform: [4][4]float32{
+			{c, s, 0, 0},
+			{-s, c, 0, 0},
+			{0, 0, 1, 0},
+			{0, 0, 0, 1},
+		},
+		Time: float32(seconds),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bind it and draw.
+	vk.CmdBindDescriptorSets(cmd, vk.PipelineBindPointGraphics, hooks.app.Pipeline().PipelineLayout,
+		0, 1, []vk.DescriptorSet{hooks.set},
+		1, []uint32{offset})
+	return hooks.meshHooks.OnRecord(cmd, imageIndex)
+}
+
+func (hooks *spinHooks) OnCleanup() {
+	vk.DestroyDescriptorPool(hooks.app.Device(), hooks.pool, nil)
+	hooks.pool = vk.DescriptorPool(vk.NullHandle)
+	hooks.set = vk.DescriptorSet(vk.NullHandle)
+	if hooks.ring != nil {
+		hooks.ring.Cleanup()
+		hooks.ring = nil
+	}
+	hooks.meshHooks.OnCleanup()
+}
@@ -0,0 +1,153 @@
+// Package golden compares headless renders against reference PNG files.
+package golden
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example.net/vulkan-tutorial/renderer"
+)
+
+// Options controls how a render is compared with its golden file.
+type Options struct {
+	// Frames to render before reading back the color attachment, at least
+	// 1.
+	Frames int
+
+	// Largest allowed difference in any one channel of a pixel.
+	Tolerance uint8
+
+	// Write the render as the new golden file instead of comparing.
+	Update bool
+}
+
+// Mismatch is returned when a render doesn't match its golden file.
+type Mismatch struct {
+	GoldenFile string
+	GotFile    string
+	DiffFile   string
+	Pixels     int
+	Total      int
+}
+
+func (err *Mismatch) Error() string {
+	return fmt.Sprintf("%d of %d pixels differ from %s, see %s and %s",
+		err.Pixels, err.Total,
+		err.GoldenFile,
+		err.GotFile, err.DiffFile)
+}
+
+// Run renders the application headless and compares the last frame with
+// the golden file. On a mismatch the render and a diff image are written
+// next to the golden file and a *Mismatch is returned.
+func Run(app *renderer.TriangleApplication, goldenFile string, opts Options) error {
+	// Render the frames.
+	got, err := app.RenderHeadless(opts.Frames)
+	if err != nil {
+		return fmt.Errorf("failed to render: %w", err)
+	}
+
+	// Replace the golden file if asked to.
+	if opts.Update {
+		if err := os.MkdirAll(filepath.Dir(goldenFile), 0755); err != nil {
+			return err
+		}
+		return renderer.SavePNG(goldenFile, got)
+	}
+
+	// Load the golden file.
+	want, err := Load(goldenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w; render with Update set to create it", err)
+	} else if err != nil {
+		return err
+	}
+
+	// Compare.
+	pixels, diff := Compare(got, want, opts.Tolerance)
+	if pixels == 0 {
+		return nil
+	}
+
+	// Save the evidence.
+	base := strings.TrimSuffix(goldenFile, ".png")
+	mismatch := &Mismatch{
+		GoldenFile: goldenFile,
+		GotFile:    base + ".got.png",
+		DiffFile:   base + ".diff.png",
+		Pixels:     pixels,
+		Total:      diff.Bounds().Dx() * diff.Bounds().Dy(),
+	}
+	if err := renderer.SavePNG(mismatch.GotFile, got); err != nil {
+		return err
+	}
+	if err := renderer.SavePNG(mismatch.DiffFile, diff); err != nil {
+		return err
+	}
+	return mismatch
+}
+
+// Load a PNG file.
+func Load(fn string) (image.Image, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", fn, err)
+	}
+	return img, nil
+}
+
+// Compare two images channel by channel. It returns the number of pixels
+// where any channel differs by more than tolerance, and a diff image with
+// those pixels in red over a faded copy of want. Images of different sizes
+// differ at every pixel outside their intersection.
+func Compare(got, want image.Image, tolerance uint8) (int, *image.RGBA) {
+	bounds := got.Bounds().Union(want.Bounds())
+	diff := image.NewRGBA(bounds)
+	red := color.RGBA{R: 0xff, A: 0xff}
+
+	pixels := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pt := image.Pt(x, y)
+			if !pt.In(got.Bounds()) || !pt.In(want.Bounds()) {
+				diff.SetRGBA(x, y, red)
+				pixels++
+				continue
+			}
+
+			g := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA)
+			w := color.NRGBAModel.Convert(want.At(x, y)).(color.NRGBA)
+			if channelDiff(g.R, w.R) > tolerance ||
+				channelDiff(g.G, w.G) > tolerance ||
+				channelDiff(g.B, w.B) > tolerance ||
+				channelDiff(g.A, w.A) > tolerance {
+				diff.SetRGBA(x, y, red)
+				pixels++
+				continue
+			}
+
+			// Fade matching pixels so the mismatches stand out.
+			diff.SetRGBA(x, y, color.RGBA{R: w.R / 4, G: w.G / 4, B: w.B / 4, A: 0xff})
+		}
+	}
+	return pixels, diff
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,86 @@
+package golden
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"example.net/vulkan-tutorial/renderer"
+)
+
+var update = flag.Bool("update", false, "write the renders to the golden files instead of comparing")
+
+// Render the application headless and fail the test if the result doesn't
+// match the golden file. The test is skipped on machines without a Vulkan
+// loader, driver or device.
+func check(t *testing.T, app *renderer.TriangleApplication, goldenFile string, opts Options) {
+	t.Helper()
+	opts.Update = *update
+	err := Run(app, goldenFile, opts)
+	if errors.Is(err, renderer.ErrNoVulkan) || errors.Is(err, renderer.ErrInitFailed) {
+		t.Skipf("no Vulkan device to render with: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTriangle(t *testing.T) {
+	// The shaders are compiled outside of the Go build.
+	for _, fn := range []string{"../shaders/vert.spv", "../shaders/frag.spv"} {
+		if _, err := os.Stat(fn); err != nil {
+			t.Skipf("%s is not compiled: %v", fn, err)
+		}
+	}
+
+	app := &renderer.TriangleApplication{
+		RequiredInstanceExtensionNames: []string{},
+		RequiredInstanceLayerNames:     []string{},
+		SelectPhysicalDeviceIndex:      renderer.DefaultSelectPhysicalDeviceIndex,
+		RequiredDeviceLayerNames:       []string{},
+		RequiredDeviceExtensionNames:   []string{},
+		VertexShaderFile:               "../shaders/vert.spv",
+		FragmentShaderFile:             "../shaders/frag.spv",
+	}
+	check(t, app, "testdata/triangle.png", Options{Frames: 1, Tolerance: 2})
+}
+
+func TestCompare(t *testing.T) {
+	solid := func(w, h int, c color.RGBA) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+		return img
+	}
+	gray := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+
+	tests := []struct {
+		name      string
+		got, want image.Image
+		tolerance uint8
+		pixels    int
+	}{
+		{"same", solid(4, 4, gray), solid(4, 4, gray), 0, 0},
+		{"within tolerance", solid(4, 4, color.RGBA{R: 102, G: 98, B: 100, A: 255}), solid(4, 4, gray), 2, 0},
+		{"over tolerance", solid(4, 4, color.RGBA{R: 103, G: 100, B: 100, A: 255}), solid(4, 4, gray), 2, 16},
+		{"alpha", solid(4, 4, color.RGBA{R: 100, G: 100, B: 100, A: 0}), solid(4, 4, gray), 2, 16},
+		{"different sizes", solid(4, 4, gray), solid(4, 2, gray), 0, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pixels, diff := Compare(tt.got, tt.want, tt.tolerance)
+			if pixels != tt.pixels {
+				t.Errorf("Compare() = %d pixels, want %d", pixels, tt.pixels)
+			}
+			if diff.Bounds() != tt.got.Bounds().Union(tt.want.Bounds()) {
+				t.Errorf("diff bounds = %v", diff.Bounds())
+			}
+		})
+	}
+}
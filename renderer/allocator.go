@@ -0,0 +1,262 @@
+package renderer
+
+import (
+	"fmt"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Size of the blocks the allocator gets from the device. Requests larger
+// than half a block get a block of their own.
+const DefaultMemoryBlockSize = vk.DeviceSize(64 << 20)
+
+// MemoryAllocation is a range of a block of device memory.
+type MemoryAllocation struct {
+	Memory    vk.DeviceMemory
+	Offset    vk.DeviceSize
+	Size      vk.DeviceSize
+	TypeIndex uint32
+
+	block *allocatorBlock
+}
+
+type allocatorBlock struct {
+	*MemoryBlock
+	memory    vk.DeviceMemory
+	typeIndex uint32
+	mapped    unsafe.Pointer
+}
+
+// Allocator sub-allocates buffers and images out of large blocks of device
+// memory, since devices only allow a few thousand allocations.
+type Allocator struct {
+	BlockSize        vk.DeviceSize // DefaultMemoryBlockSize if zero.
+	MemoryProperties vk.PhysicalDeviceMemoryProperties
+	Granularity      vk.DeviceSize
+
+	device vk.Device
+	blocks map[uint32][]*allocatorBlock // By memory type.
+}
+
+// NewAllocator creates an allocator for the device.
+func NewAllocator(device vk.Device, phyDev PhysicalDevice) *Allocator {
+	// Create the result object.
+	allocator := &Allocator{
+		Granularity: phyDev.Properties.Limits.BufferImageGranularity,
+		device:      device,
+		blocks:      make(map[uint32][]*allocatorBlock),
+	}
+
+	// Get the memory types and heaps.
+	vk.GetPhysicalDeviceMemoryProperties(phyDev.Handle, &allocator.MemoryProperties)
+	allocator.MemoryProperties.Deref()
+	for h := uint32(0); h < allocator.MemoryProperties.MemoryTypeCount; h++ {
+		allocator.MemoryProperties.MemoryTypes[h].Deref()
+	}
+	for h := uint32(0); h < allocator.MemoryProperties.MemoryHeapCount; h++ {
+		allocator.MemoryProperties.MemoryHeaps[h].Deref()
+	}
+	return allocator
+}
+
+// Allocate memory for a resource with the requirements. The memory type has
+// all of the required properties and as many of the preferred ones as
+// possible. linear is false for images with optimal tiling.
+func (allocator *Allocator) Allocate(memReqs vk.MemoryRequirements, required, preferred vk.MemoryPropertyFlags, linear bool) (MemoryAllocation, error) {
+	// Pick the memory type.
+	memType := ChooseMemoryType(allocator.MemoryProperties, memReqs.MemoryTypeBits, required, preferred)
+	if !memType.IsSet() {
+		return MemoryAllocation{}, fmt.Errorf("no memory type with properties %#x in type bits %#b",
+			required, memReqs.MemoryTypeBits)
+	}
+	typeIndex := memType.Val()
+
+	// Try the existing blocks.
+	for _, block := range allocator.blocks[typeIndex] {
+		if offset, ok := block.Allocate(memReqs.Size, memReqs.Alignment, linear); ok {
+			return block.allocation(offset, memReqs.Size), nil
+		}
+	}
+
+	// Get a new block from the device.
+	blockSize := allocator.BlockSize
+	if blockSize == 0 {
+		blockSize = DefaultMemoryBlockSize
+	}
+	if memReqs.Size > blockSize/2 {
+		blockSize = memReqs.Size
+	}
+	block, err := allocator.newBlock(typeIndex, blockSize)
+	if err != nil {
+		return MemoryAllocation{}, err
+	}
+	offset, ok := block.Allocate(memReqs.Size, memReqs.Alignment, linear)
+	if !ok {
+		allocator.freeBlock(block)
+		return MemoryAllocation{}, fmt.Errorf("%d bytes don't fit in a new %d byte block", memReqs.Size, blockSize)
+	}
+	return block.allocation(offset, memReqs.Size), nil
+}
+
+// Create a buffer and bind it to sub-allocated memory.
+func (allocator *Allocator) AllocateBuffer(bufferInfo vk.BufferCreateInfo, required, preferred vk.MemoryPropertyFlags) (vk.Buffer, MemoryAllocation, error) {
+	// Call the Vulkan function.
+	var buffer vk.Buffer
+	err := CheckResultInfo("vkCreateBuffer", vk.CreateBuffer(allocator.device, &bufferInfo, nil, &buffer),
+		fmt.Sprintf("size=%d usage=%#x", bufferInfo.Size, bufferInfo.Usage))
+	if err != nil {
+		return buffer, MemoryAllocation{}, err
+	}
+
+	// Find out what memory the buffer needs.
+	var memReqs vk.MemoryRequirements
+	vk.GetBufferMemoryRequirements(allocator.device, buffer, &memReqs)
+	memReqs.Deref()
+
+	// Allocate and bind the memory.
+	alloc, err := allocator.Allocate(memReqs, required, preferred, true)
+	if err != nil {
+		vk.DestroyBuffer(allocator.device, buffer, nil)
+		return vk.Buffer(vk.NullHandle), alloc, err
+	}
+	err = CheckResult("vkBindBufferMemory", vk.BindBufferMemory(allocator.device, buffer, alloc.Memory, alloc.Offset))
+	if err != nil {
+		vk.DestroyBuffer(allocator.device, buffer, nil)
+		allocator.Free(alloc)
+		return vk.Buffer(vk.NullHandle), MemoryAllocation{}, err
+	}
+	return buffer, alloc, nil
+}
+
+// Create an image and bind it to sub-allocated memory.
+func (allocator *Allocator) AllocateImage(imageInfo vk.ImageCreateInfo, required, preferred vk.MemoryPropertyFlags) (vk.Image, MemoryAllocation, error) {
+	// Call the Vulkan function.
+	var image vk.Image
+	err := CheckResultInfo("vkCreateImage", vk.CreateImage(allocator.device, &imageInfo, nil, &image),
+		fmt.Sprintf("format=%d extent=%dx%d usage=%#x",
+			imageInfo.Format,
+			imageInfo.Extent.Width, imageInfo.Extent.Height,
+			imageInfo.Usage))
+	if err != nil {
+		return image, MemoryAllocation{}, err
+	}
+
+	// Find out what memory the image needs.
+	var memReqs vk.MemoryRequirements
+	vk.GetImageMemoryRequirements(allocator.device, image, &memReqs)
+	memReqs.Deref()
+
+	// Allocate and bind the memory.
+	linear := imageInfo.Tiling == vk.ImageTilingLinear
+	alloc, err := allocator.Allocate(memReqs, required, preferred, linear)
+	if err != nil {
+		vk.DestroyImage(allocator.device, image, nil)
+		return vk.Image(vk.NullHandle), alloc, err
+	}
+	err = CheckResult("vkBindImageMemory", vk.BindImageMemory(allocator.device, image, alloc.Memory, alloc.Offset))
+	if err != nil {
+		vk.DestroyImage(allocator.device, image, nil)
+		allocator.Free(alloc)
+		return vk.Image(vk.NullHandle), MemoryAllocation{}, err
+	}
+	return image, alloc, nil
+}
+
+// Map returns a pointer to the allocation. The memory must be host visible.
+// Blocks are mapped once and stay mapped until they are freed.
+func (allocator *Allocator) Map(alloc MemoryAllocation) (unsafe.Pointer, error) {
+	block := alloc.block
+	if block == nil {
+		return nil, fmt.Errorf("map: not an allocation")
+	}
+	flags := allocator.MemoryProperties.MemoryTypes[block.typeIndex].PropertyFlags
+	if flags&vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit) == 0 {
+		return nil, fmt.Errorf("map: memory type %d is not host visible", block.typeIndex)
+	}
+	if block.mapped == nil {
+		err := CheckResult("vkMapMemory", vk.MapMemory(allocator.device, block.memory, 0, vk.DeviceSize(vk.WholeSize), 0, &block.mapped))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return unsafe.Pointer(uintptr(block.mapped) + uintptr(alloc.Offset)), nil
+}
+
+// Free an allocation. Blocks with nothing left in them are given back to
+// the device.
+func (allocator *Allocator) Free(alloc MemoryAllocation) {
+	block := alloc.block
+	if block == nil {
+		return
+	}
+	if err := block.Free(alloc.Offset); err != nil {
+		fmt.Printf("Allocator: %v\n", err)
+		return
+	}
+	if block.Empty() {
+		allocator.freeBlock(block)
+	}
+}
+
+// Give all of the blocks back to the device. Every resource using them must
+// already be destroyed.
+func (allocator *Allocator) Cleanup() {
+	for _, blocks := range allocator.blocks {
+		for _, block := range append([]*allocatorBlock{}, blocks...) {
+			allocator.freeBlock(block)
+		}
+	}
+}
+
+func (allocator *Allocator) newBlock(typeIndex uint32, size vk.DeviceSize) (*allocatorBlock, error) {
+	// Create the info object.
+	allocInfo := vk.MemoryAllocateInfo{
+		SType:           vk.StructureTypeMemoryAllocateInfo,
+		AllocationSize:  size,
+		MemoryTypeIndex: typeIndex,
+	}
+
+	// Create the result object.
+	block := &allocatorBlock{
+		MemoryBlock: NewMemoryBlock(size, allocator.Granularity),
+		typeIndex:   typeIndex,
+	}
+
+	// Call the Vulkan function.
+	err := CheckResultInfo("vkAllocateMemory", vk.AllocateMemory(allocator.device, &allocInfo, nil, &block.memory),
+		fmt.Sprintf("size=%d type=%d", allocInfo.AllocationSize, allocInfo.MemoryTypeIndex))
+	if err != nil {
+		return nil, err
+	}
+	allocator.blocks[typeIndex] = append(allocator.blocks[typeIndex], block)
+	return block, nil
+}
+
+func (allocator *Allocator) freeBlock(block *allocatorBlock) {
+	if block.mapped != nil {
+		vk.UnmapMemory(allocator.device, block.memory)
+		block.mapped = nil
+	}
+	vk.FreeMemory(allocator.device, block.memory, nil)
+	block.memory = vk.DeviceMemory(vk.NullHandle)
+
+	// Remove it from the list.
+	blocks := allocator.blocks[block.typeIndex]
+	for k, v := range blocks {
+		if v == block {
+			allocator.blocks[block.typeIndex] = append(blocks[:k], blocks[k+1:]...)
+			break
+		}
+	}
+}
+
+func (block *allocatorBlock) allocation(offset, size vk.DeviceSize) MemoryAllocation {
+	return MemoryAllocation{
+		Memory:    block.memory,
+		Offset:    offset,
+		Size:      size,
+		TypeIndex: block.typeIndex,
+		block:     block,
+	}
+}
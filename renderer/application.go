@@ -0,0 +1,878 @@
+package renderer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+const (
+	WindowWidth  = 800
+	WindowHeight = 600
+
+	DefaultVertexShaderFile   = "shaders/vert.spv"
+	DefaultFragmentShaderFile = "shaders/frag.spv"
+
+	DefaultFramesInFlight = 2
+)
+
+// TriangleApplication owns the window, the Vulkan instance and device, and
+// the frame loop. Run must be called from the main OS thread.
+type TriangleApplication struct {
+	window                         *glfw.Window
+	instance                       vk.Instance
+	RequiredInstanceExtensionNames []string
+	RequiredInstanceLayerNames     []string
+
+	surface                   vk.Surface
+	physicalDevice            PhysicalDevice
+	SelectPhysicalDeviceIndex func([]PhysicalDevice, vk.Surface) int
+
+	RequiredDeviceExtensionNames []string
+	RequiredDeviceLayerNames     []string
+	enabledFeatures              vk.PhysicalDeviceFeatures
+	device                       vk.Device
+	graphicsQueue                vk.Queue
+	presentationQueue            vk.Queue
+	allocator                    *Allocator
+
+	VertexShaderFile    string
+	FragmentShaderFile  string
+	PipelineDescs       []GraphicsPipelineDesc // One pipeline each, the default if empty.
+	PipelineCacheDir    string                 // Where compiled pipelines are kept between runs.
+	pipelineCache       *PipelineCache
+	SurfaceFormats      []vk.SurfaceFormat     // Swapchain format preferences, DefaultSurfaceFormats if empty.
+	DepthFormats        []vk.Format            // Depth buffer format preferences, no depth buffer if empty.
+	Samples             vk.SampleCountFlagBits // MSAA samples, clamped to what the device supports. 1 if zero.
+	extendedColorSpaces bool
+	PresentPolicy       PresentPolicy // Change with SetPresentPolicy once running.
+	SwapchainImageCount uint32        // MinImageCount + 1 if zero.
+	swapchain           *Swapchain
+	pipeline            *Pipeline
+	graphicsCommandPool vk.CommandPool
+
+	imageAvailableSemaphores []vk.Semaphore
+	renderFinishedSemaphores []vk.Semaphore
+	inFlightFences           []vk.Fence
+	imagesInFlight           []vk.Fence
+	currentFrame             uint
+	uniformRings             []*UniformRing
+	FramesInFlight           uint // DefaultFramesInFlight if zero. Change with SetFramesInFlight once running.
+
+	framebufferResize    bool
+	presentPolicyChanged bool
+
+	Headless       bool
+	HeadlessExtent vk.Extent2D
+
+	Hooks      ApplicationHooks
+	hooksSetup bool
+	lastFrame  time.Time
+}
+
+// Accessors for hooks.
+func (app *TriangleApplication) Window() *glfw.Window                { return app.window }
+func (app *TriangleApplication) Instance() vk.Instance               { return app.instance }
+func (app *TriangleApplication) PhysicalDevice() PhysicalDevice      { return app.physicalDevice }
+func (app *TriangleApplication) Device() vk.Device                   { return app.device }
+func (app *TriangleApplication) GraphicsQueue() vk.Queue             { return app.graphicsQueue }
+func (app *TriangleApplication) Allocator() *Allocator               { return app.allocator }
+func (app *TriangleApplication) GraphicsCommandPool() vk.CommandPool { return app.graphicsCommandPool }
+func (app *TriangleApplication) Swapchain() *Swapchain               { return app.swapchain }
+func (app *TriangleApplication) Pipeline() *Pipeline                 { return app.pipeline }
+
+func (app *TriangleApplication) hooks() ApplicationHooks {
+	if app.Hooks == nil {
+		return TriangleHooks{}
+	}
+	return app.Hooks
+}
+
+func (app *TriangleApplication) setup() error {
+	// Steps.
+	createWindow := func() error {
+		// Headless applications don't have a window.
+		if app.Headless {
+			return nil
+		}
+
+		// Initialize GLFW
+		err := glfw.Init()
+		if err != nil {
+			return fmt.Errorf("glfwInit failed: %w", err)
+		}
+
+		// Tell GLFW we aren't using OpenGL.
+		glfw.WindowHint(glfw.ClientAPI, glfw.NoAPI)
+
+		// Create the window object.
+		app.window, err = glfw.CreateWindow(WindowWidth, WindowHeight, "Vulkan", nil, nil)
+		if err != nil {
+			return fmt.Errorf("glfwCreateWindow failed: %w", err)
+		}
+
+		// Callback for the framebuffer size changing.
+		app.window.SetFramebufferSizeCallback(func(*glfw.Window, int, int) {
+			app.framebufferResize = true
+		})
+
+		// Update required extensions.
+		app.RequiredInstanceExtensionNames = append(
+			app.RequiredInstanceExtensionNames,
+			app.window.GetRequiredInstanceExtensions()...,
+		)
+		return nil
+	}
+
+	initVulkan := func() error {
+		if app.Headless {
+			// Load the Vulkan loader directly.
+			if err := vk.SetDefaultGetInstanceProcAddr(); err != nil {
+				return fmt.Errorf("%w: vulkan loader not found: %v", ErrNoVulkan, err)
+			}
+		} else {
+			// Link Vulkan and GLFW
+			vk.SetGetInstanceProcAddr(glfw.GetVulkanGetInstanceProcAddress())
+		}
+
+		// Initialize Vulkan
+		if err := vk.Init(); err != nil {
+			return fmt.Errorf("%w: vulkan init failed: %v", ErrNoVulkan, err)
+		}
+		return nil
+	}
+
+	createInstance := func() error {
+		// Available Instance Layers.
+		layerProps := EnumerateInstanceLayerProperties()
+		availLayerNames, availLayerDescs := LayerPropertiesNamesAndDescriptions(layerProps)
+		for h := 0; h < len(layerProps); h++ {
+			fmt.Printf("Layer Avail: %s %s\n",
+				availLayerNames[h],
+				availLayerDescs[h])
+		}
+
+		// Required Instance Layers.
+		reqLayerNames := ToCStrings(DedupeSlice(app.RequiredInstanceLayerNames))
+		if err := CheckSupport(availLayerNames, reqLayerNames); err != nil {
+			return fmt.Errorf("instance layers: %w", err)
+		}
+
+		// Available Instance Extensions.
+		layerExts := EnumerateInstanceExtensionProperties("")
+		availExtNames := ExtensionPropertiesNames(layerExts)
+		for h := 0; h < len(layerExts); h++ {
+			fmt.Printf("Extension Avail: %s\n",
+				availExtNames[h])
+		}
+
+		// Color spaces other than sRGB need an extension. Enable it when
+		// it is available and a preference uses one.
+		if !app.Headless && NeedsExtendedColorSpaces(app.SurfaceFormats) {
+			if SliceToMap(availExtNames)[ToCString(vk.ExtSwapchainColorspaceExtensionName)] {
+				app.RequiredInstanceExtensionNames = append(
+					app.RequiredInstanceExtensionNames,
+					vk.ExtSwapchainColorspaceExtensionName,
+				)
+				app.extendedColorSpaces = true
+			} else {
+				fmt.Printf("%s is not available, only sRGB surface formats will be used\n",
+					vk.ExtSwapchainColorspaceExtensionName)
+			}
+		}
+
+		// Required Instance Extensions.
+		reqExtNames := ToCStrings(DedupeSlice(app.RequiredInstanceExtensionNames))
+		if err := CheckSupport(availExtNames, reqExtNames); err != nil {
+			return fmt.Errorf("instance extensions: %w", err)
+		}
+
+		// Create the info object.
+		instanceInfo := vk.InstanceCreateInfo{
+			SType: vk.StructureTypeInstanceCreateInfo,
+			PApplicationInfo: &vk.ApplicationInfo{
+				SType:              vk.StructureTypeApplicationInfo,
+				PApplicationName:   ToCString("Hello Triangle"),
+				ApplicationVersion: vk.MakeVersion(1, 0, 0),
+				PEngineName:        ToCString("No Engine"),
+				EngineVersion:      vk.MakeVersion(1, 0, 0),
+				ApiVersion:         vk.ApiVersion11,
+			},
+			EnabledExtensionCount:   uint32(len(reqExtNames)),
+			PpEnabledExtensionNames: reqExtNames,
+			EnabledLayerCount:       uint32(len(reqLayerNames)),
+			PpEnabledLayerNames:     reqLayerNames,
+		}
+
+		// Create the result object.
+		var instance vk.Instance
+
+		// Call the Vulkan function.
+		err := CheckResultInfo("vkCreateInstance", vk.CreateInstance(&instanceInfo, nil, &instance),
+			fmt.Sprintf("layers=%v extensions=%v",
+				DedupeSlice(app.RequiredInstanceLayerNames),
+				DedupeSlice(app.RequiredInstanceExtensionNames)))
+		if err != nil {
+			return err
+		}
+
+		// Update the application.
+		app.instance = instance
+
+		// InitInstance is required for macOs?
+		if err := vk.InitInstance(app.instance); err != nil {
+			return fmt.Errorf("vulkan instance init failed: %w", err)
+		}
+		return nil
+	}
+
+	createSurface := func() error {
+		// Headless applications don't present.
+		if app.Headless {
+			return nil
+		}
+
+		// Get the surface from the Window.
+		surface, err := app.window.CreateWindowSurface(app.instance, nil)
+		if err != nil {
+			return fmt.Errorf("glfwCreateWindowSurface failed: %w", err)
+		}
+
+		// Store the handle
+		app.surface = vk.SurfaceFromPointer(surface)
+		return nil
+	}
+
+	pickPhysicalDevice := func() error {
+		// Output all the physical devices.
+		physicalDevices := EnumeratePhysicalDevices(app.instance)
+		for k, phyDev := range physicalDevices {
+			fmt.Printf("Physical Device Avail %d: %v\n", k, phyDev)
+		}
+		if len(physicalDevices) == 0 {
+			return fmt.Errorf("%w: the instance has no physical devices", ErrNoVulkan)
+		}
+
+		// Filter devices based on required support.
+		filteredPhysicalDevices := make([]PhysicalDevice, 0, len(physicalDevices))
+		for _, phyDev := range physicalDevices {
+			// Get device layer support.
+			availLayerNames, _ := LayerPropertiesNamesAndDescriptions(
+				phyDev.LayerProperties,
+			)
+
+			// Calculate missing layers.
+			missingLayerNames := SetSubtraction(
+				app.RequiredDeviceLayerNames,
+				SliceToMap(availLayerNames),
+			)
+
+			// Get device extension support.
+			availExtNames := ExtensionPropertiesNames(
+				phyDev.ExtensionProperties,
+			)
+
+			// Calculate missing extensions.
+			missingExtNames := SetSubtraction(
+				app.RequiredDeviceExtensionNames,
+				SliceToMap(availExtNames),
+			)
+
+			// Add supported devices.
+			if len(missingLayerNames) == 0 && len(missingExtNames) == 0 {
+				filteredPhysicalDevices = append(
+					filteredPhysicalDevices,
+					phyDev,
+				)
+			}
+		}
+		physicalDevices = filteredPhysicalDevices
+
+		// fail if we have zero of them.
+		if len(physicalDevices) == 0 {
+			return fmt.Errorf("failed to find GPUs with Vulkan support!")
+		}
+
+		// Ask the application to select a device.
+		selectIndex := app.SelectPhysicalDeviceIndex
+		if selectIndex == nil {
+			selectIndex = DefaultSelectPhysicalDeviceIndex
+		}
+		idx := selectIndex(physicalDevices,
+			app.surface)
+		if idx < 0 || idx >= len(physicalDevices) {
+			return fmt.Errorf("failed to select a physical device, got index %d", idx)
+		}
+		app.physicalDevice = physicalDevices[idx]
+		return nil
+	}
+
+	createLogicalDevice := func() error {
+		// Calculate the number of queue info structs.
+		gIdx, pIdx := app.physicalDevice.QueueFamilies(app.surface)
+		queueFamilyIndices := []uint32{gIdx.Val(), pIdx.Val()}
+		if gIdx.Val() == pIdx.Val() {
+			queueFamilyIndices = queueFamilyIndices[:1]
+		}
+
+		// Populate the queue infos.
+		queueCreateInfos := make([]vk.DeviceQueueCreateInfo, len(queueFamilyIndices))
+		for k, idx := range queueFamilyIndices {
+			queueCreateInfos[k] = vk.DeviceQueueCreateInfo{
+				SType:            vk.StructureTypeDeviceQueueCreateInfo,
+				QueueFamilyIndex: idx,
+				QueueCount:       1,
+				PQueuePriorities: []float32{1.0},
+			}
+		}
+
+		// Enable the optional features pipeline descriptions can use, where
+		// the device has them.
+		supported := app.physicalDevice.Features
+		app.enabledFeatures = vk.PhysicalDeviceFeatures{
+			FillModeNonSolid:  supported.FillModeNonSolid,
+			WideLines:         supported.WideLines,
+			SampleRateShading: supported.SampleRateShading,
+		}
+
+		// Create the info object.
+		deviceInfo := vk.DeviceCreateInfo{
+			SType:                   vk.StructureTypeDeviceCreateInfo,
+			QueueCreateInfoCount:    uint32(len(queueCreateInfos)),
+			PQueueCreateInfos:       queueCreateInfos,
+			EnabledLayerCount:       uint32(len(app.RequiredDeviceLayerNames)),
+			PpEnabledLayerNames:     ToCStrings(app.RequiredDeviceLayerNames),
+			EnabledExtensionCount:   uint32(len(app.RequiredDeviceExtensionNames)),
+			PpEnabledExtensionNames: ToCStrings(app.RequiredDeviceExtensionNames),
+			PEnabledFeatures:        []vk.PhysicalDeviceFeatures{app.enabledFeatures},
+		}
+
+		// Create the result object.
+		var device vk.Device
+
+		// Call the Vulkan function.
+		err := CheckResultInfo("vkCreateDevice", vk.CreateDevice(app.physicalDevice.Handle, &deviceInfo, nil, &device),
+			fmt.Sprintf("device=%s queueFamilies=%v layers=%v extensions=%v",
+				vk.ToString(app.physicalDevice.Properties.DeviceName[:]),
+				queueFamilyIndices,
+				app.RequiredDeviceLayerNames,
+				app.RequiredDeviceExtensionNames))
+		if err != nil {
+			return err
+		}
+
+		// Update the application.
+		app.device = device
+
+		// Fetch the graphics queue handle.
+		var queue vk.Queue
+		queueIndex := 0
+		vk.GetDeviceQueue(app.device, queueFamilyIndices[queueIndex], uint32(queueIndex), &queue)
+		app.graphicsQueue = queue
+
+		// Fetch the presentation queue handle.
+		queueIndex = len(queueFamilyIndices) - 1
+		vk.GetDeviceQueue(app.device, queueFamilyIndices[queueIndex], uint32(queueIndex), &queue)
+		app.presentationQueue = queue
+		return nil
+	}
+
+	createAllocator := func() error {
+		app.allocator = NewAllocator(app.device, app.physicalDevice)
+		return nil
+	}
+
+	createPipelineCache := func() (err error) {
+		app.pipelineCache, err = NewPipelineCache(app.device, app.physicalDevice, app.PipelineCacheDir)
+		return err
+	}
+
+	createCommandPool := func() error {
+		// Get the queue families
+		gIdx, _ := app.physicalDevice.QueueFamilies(app.surface)
+
+		// Create the info object.
+		poolInfo := vk.CommandPoolCreateInfo{
+			SType:            vk.StructureTypeCommandPoolCreateInfo,
+			Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateResetCommandBufferBit),
+			QueueFamilyIndex: gIdx.Val(),
+		}
+
+		// Create the result object.
+		var commandPool vk.CommandPool
+
+		// Call the Vulkan function.
+		err := CheckResultInfo("vkCreateCommandPool", vk.CreateCommandPool(app.device, &poolInfo, nil, &commandPool),
+			fmt.Sprintf("queueFamily=%d", poolInfo.QueueFamilyIndex))
+		if err != nil {
+			return err
+		}
+
+		// Update the application.
+		app.graphicsCommandPool = commandPool
+		return nil
+	}
+
+	checkFramesInFlight := func() error {
+		if app.FramesInFlight == 0 {
+			app.FramesInFlight = DefaultFramesInFlight
+		}
+		return app.validateFramesInFlight(app.FramesInFlight)
+	}
+
+	setupHooks := func() error {
+		// Let the application create its resources.
+		if err := app.hooks().OnSetup(app); err != nil {
+			return fmt.Errorf("OnSetup hook failed: %w", err)
+		}
+		app.hooksSetup = true
+		app.lastFrame = time.Now()
+		return nil
+	}
+
+	// Calls
+	steps := []func() error{
+		createWindow,
+		initVulkan,
+		createInstance,
+		createSurface,
+		pickPhysicalDevice,
+		createLogicalDevice,
+		createAllocator,
+		createPipelineCache,
+		createCommandPool,
+		app.recreatePipeline,
+		checkFramesInFlight,
+		app.createSemaphores,
+		app.createFences,
+		setupHooks,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *TriangleApplication) createSemaphores() error {
+	// Create the info object.
+	semaphoreInfo := vk.SemaphoreCreateInfo{
+		SType: vk.StructureTypeSemaphoreCreateInfo,
+	}
+
+	// Create the result object(s).
+	imgAvail := make([]vk.Semaphore, app.FramesInFlight)
+	renderDone := make([]vk.Semaphore, app.FramesInFlight)
+
+	// Update the application.
+	app.imageAvailableSemaphores = imgAvail
+	app.renderFinishedSemaphores = renderDone
+
+	// Call the Vulkan function...
+	for h := 0; h < len(imgAvail); h++ {
+		// ... for image available.
+		err := CheckResult("vkCreateSemaphore", vk.CreateSemaphore(app.device, &semaphoreInfo, nil, &imgAvail[h]))
+		if err != nil {
+			return err
+		}
+
+		// ... for render finished.
+		err = CheckResult("vkCreateSemaphore", vk.CreateSemaphore(app.device, &semaphoreInfo, nil, &renderDone[h]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *TriangleApplication) createFences() error {
+	// Create the info object.
+	fenceInfo := vk.FenceCreateInfo{
+		SType: vk.StructureTypeFenceCreateInfo,
+		Flags: vk.FenceCreateFlags(vk.FenceCreateSignaledBit),
+	}
+
+	// Create the result object.
+	inFlightFences := make([]vk.Fence, app.FramesInFlight)
+
+	// Update the application.
+	app.inFlightFences = inFlightFences
+
+	// Call the Vulkan function.
+	for k, _ := range inFlightFences {
+		err := CheckResult("vkCreateFence", vk.CreateFence(app.device, &fenceInfo, nil, &inFlightFences[k]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *TriangleApplication) cleanupSyncObjects() {
+	for _, fence := range app.inFlightFences {
+		vk.DestroyFence(app.device, fence, nil)
+	}
+	for _, semaphore := range app.renderFinishedSemaphores {
+		vk.DestroySemaphore(app.device, semaphore, nil)
+	}
+	for _, semaphore := range app.imageAvailableSemaphores {
+		vk.DestroySemaphore(app.device, semaphore, nil)
+	}
+	app.inFlightFences = nil
+	app.renderFinishedSemaphores = nil
+	app.imageAvailableSemaphores = nil
+
+	// The image trackers point at the fences that were just destroyed.
+	for k := range app.imagesInFlight {
+		app.imagesInFlight[k] = vk.Fence(vk.NullHandle)
+	}
+	app.currentFrame = 0
+}
+
+// More frames in flight than swapchain images would only wait on the
+// images, so the swapchain image count is the limit.
+func (app *TriangleApplication) validateFramesInFlight(frames uint) error {
+	if frames == 0 {
+		return fmt.Errorf("frames in flight must be at least 1")
+	}
+	if app.swapchain != nil && !app.swapchain.Offscreen() && frames > uint(len(app.swapchain.Images)) {
+		return fmt.Errorf("%d frames in flight requested, the swapchain only has %d images",
+			frames, len(app.swapchain.Images))
+	}
+	for _, ring := range app.uniformRings {
+		if frames > ring.Frames() {
+			return fmt.Errorf("%d frames in flight requested, a uniform ring only has %d frames",
+				frames, ring.Frames())
+		}
+	}
+	return nil
+}
+
+// SetFramesInFlight changes how many frames the CPU may record ahead of the
+// GPU. It waits for the device to go idle and recreates the semaphores and
+// fences, so it must be called between frames, not from OnRecord.
+func (app *TriangleApplication) SetFramesInFlight(frames uint) error {
+	if err := app.validateFramesInFlight(frames); err != nil {
+		return err
+	}
+	app.FramesInFlight = frames
+
+	// Before setup only the field is needed.
+	if app.device == vk.Device(vk.NullHandle) || app.inFlightFences == nil {
+		return nil
+	}
+
+	// Wait for the device to finish work.
+	err := CheckResult("vkDeviceWaitIdle", vk.DeviceWaitIdle(app.device))
+	if err != nil {
+		return err
+	}
+
+	// Replace the sync objects.
+	app.cleanupSyncObjects()
+	if err := app.createSemaphores(); err != nil {
+		return err
+	}
+	return app.createFences()
+}
+
+func (app *TriangleApplication) mainLoop() error {
+	for !app.window.ShouldClose() {
+		glfw.PollEvents()
+
+		// Update the application state.
+		now := time.Now()
+		if err := app.hooks().OnUpdate(now.Sub(app.lastFrame)); err != nil {
+			return fmt.Errorf("OnUpdate hook failed: %w", err)
+		}
+		app.lastFrame = now
+
+		if err := app.drawFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (app *TriangleApplication) drawFrame() error {
+	// Wait for Vulkan to finish with this frame.
+	err := CheckResult("vkWaitForFences", vk.WaitForFences(app.device,
+		1,
+		app.inFlightFences[app.currentFrame:],
+		vk.True,
+		vk.MaxUint64))
+	if err != nil {
+		return err
+	}
+
+	// The GPU is done with what this frame wrote to the uniform rings.
+	for _, ring := range app.uniformRings {
+		ring.beginFrame(app.currentFrame)
+	}
+
+	// Get the index of the next image. Headless frames always draw to the
+	// single offscreen image.
+	var imageIndex uint32
+	if !app.Headless {
+		ret := vk.AcquireNextImage(app.device,
+			app.swapchain.Handle,
+			vk.MaxUint64,
+			app.imageAvailableSemaphores[app.currentFrame],
+			vk.Fence(vk.NullHandle),
+			&imageIndex)
+		if ret == vk.ErrorOutOfDate {
+			return app.recreatePipeline()
+		} else if ret != vk.Success && ret != vk.Suboptimal {
+			return CheckResult("vkAcquireNextImageKHR", ret)
+		}
+	}
+
+	// Wait for Vulkan to finish with this image.
+	if app.imagesInFlight[imageIndex] != vk.Fence(vk.NullHandle) {
+		err := CheckResult("vkWaitForFences", vk.WaitForFences(app.device,
+			1,
+			app.imagesInFlight[imageIndex:],
+			vk.True,
+			vk.MaxUint64))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update inflight fences.
+	app.imagesInFlight[imageIndex] = app.inFlightFences[app.currentFrame]
+
+	// Record the commands for this image.
+	err = app.pipeline.RecordCommandBuffer(imageIndex, app.hooks().OnRecord)
+	if err != nil {
+		return err
+	}
+
+	// Headless frames have no image to wait for or to present.
+	waitSemaphores := []vk.Semaphore{
+		app.imageAvailableSemaphores[app.currentFrame],
+	}
+	signalSemaphores := []vk.Semaphore{
+		app.renderFinishedSemaphores[app.currentFrame],
+	}
+	if app.Headless {
+		waitSemaphores, signalSemaphores = nil, nil
+	}
+
+	// Create the graphics queue submit info object.
+	submitInfos := []vk.SubmitInfo{
+		vk.SubmitInfo{
+			SType:              vk.StructureTypeSubmitInfo,
+			WaitSemaphoreCount: uint32(len(waitSemaphores)),
+			PWaitSemaphores:    waitSemaphores,
+			PWaitDstStageMask: []vk.PipelineStageFlags{
+				vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+			},
+			CommandBufferCount: 1,
+			PCommandBuffers: []vk.CommandBuffer{
+				app.pipeline.GraphicsCommandBuffers[imageIndex],
+			},
+			SignalSemaphoreCount: uint32(len(signalSemaphores)),
+			PSignalSemaphores:    signalSemaphores,
+		},
+	}
+
+	// Reset the fence for this frame.
+	err = CheckResult("vkResetFences", vk.ResetFences(app.device,
+		1,
+		app.inFlightFences[app.currentFrame:]))
+	if err != nil {
+		return err
+	}
+
+	// Submit work to the graphics queue.
+	err = CheckResult("vkQueueSubmit", vk.QueueSubmit(app.graphicsQueue, 1, submitInfos, app.inFlightFences[app.currentFrame]))
+	if err != nil {
+		return err
+	}
+
+	// Nothing to present when headless.
+	if app.Headless {
+		app.currentFrame = (app.currentFrame + 1) % uint(len(app.inFlightFences))
+		return nil
+	}
+
+	// Create the present queue info object.
+	presentInfo := vk.PresentInfo{
+		SType:              vk.StructureTypePresentInfo,
+		WaitSemaphoreCount: 1,
+		PWaitSemaphores: []vk.Semaphore{
+			app.renderFinishedSemaphores[app.currentFrame],
+		},
+		SwapchainCount: 1,
+		PSwapchains: []vk.Swapchain{
+			app.swapchain.Handle,
+		},
+		PImageIndices: []uint32{imageIndex},
+	}
+
+	// Submit work to the present queue.
+	ret := vk.QueuePresent(app.presentationQueue, &presentInfo)
+	if ret == vk.ErrorOutOfDate || ret == vk.Suboptimal || app.framebufferResize || app.presentPolicyChanged {
+		err = app.recreatePipeline()
+	} else if ret != vk.Success {
+		err = CheckResult("vkQueuePresentKHR", ret)
+	}
+
+	// Update the current frame.
+	app.currentFrame = (app.currentFrame + 1) % uint(len(app.inFlightFences))
+	return err
+}
+
+// SetPresentPolicy changes how frames are paced. The swapchain is recreated
+// with the new policy after the current frame is presented.
+func (app *TriangleApplication) SetPresentPolicy(policy PresentPolicy) {
+	if policy == app.PresentPolicy {
+		return
+	}
+	app.PresentPolicy = policy
+	app.presentPolicyChanged = app.swapchain != nil
+}
+
+func (app *TriangleApplication) recreatePipeline() error {
+	// wait if the current framebuffer surface is 0
+	if !app.Headless {
+		width, height := app.window.GetFramebufferSize()
+		for width == 0 || height == 0 {
+			width, height = app.window.GetFramebufferSize()
+			glfw.WaitEvents()
+		}
+	}
+
+	// Clear the flags that asked for the swapchain to be recreated.
+	app.framebufferResize = false
+	app.presentPolicyChanged = false
+
+	// Wait for the device to finish work.
+	err := CheckResult("vkDeviceWaitIdle", vk.DeviceWaitIdle(app.device))
+	if err != nil {
+		return err
+	}
+
+	// The size of the images.
+	extent := app.headlessExtent()
+	if !app.Headless {
+		width, height := app.window.GetFramebufferSize()
+		extent = vk.Extent2D{Width: uint32(width), Height: uint32(height)}
+	}
+
+	// Create the swapchain and pipeline, or only rebuild the swapchain and
+	// framebuffers of existing ones.
+	resized := app.pipeline != nil
+	if resized {
+		app.pipeline.CleanupFramebuffers(app.device)
+		app.swapchain.Options.PresentPolicy = app.PresentPolicy
+		if err := app.swapchain.Recreate(extent); err != nil {
+			return fmt.Errorf("failed to recreate swapchain: %w", err)
+		}
+		if err := app.pipeline.RecreateFramebuffers(app); err != nil {
+			return fmt.Errorf("failed to recreate framebuffers: %w", err)
+		}
+	} else {
+		app.swapchain, err = NewSwapchain(app.device, app.allocator, app.physicalDevice, app.surface, extent, SwapchainOptions{
+			SurfaceFormats:      app.SurfaceFormats,
+			ExtendedColorSpaces: app.extendedColorSpaces,
+			PresentPolicy:       app.PresentPolicy,
+			ImageCount:          app.SwapchainImageCount,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create swapchain: %w", err)
+		}
+		app.pipeline, err = NewPipeline(app, app.swapchain)
+		if err != nil {
+			return fmt.Errorf("failed to create pipeline: %w", err)
+		}
+	}
+
+	// Allocate Images in flight tracker.
+	app.imagesInFlight = make([]vk.Fence, len(app.swapchain.Images))
+
+	// The new swapchain may have fewer images than there are frames in
+	// flight. Before setup is done checkFramesInFlight reports it instead.
+	if images := uint(len(app.swapchain.Images)); app.inFlightFences != nil && !app.swapchain.Offscreen() && app.FramesInFlight > images {
+		fmt.Printf("Frames in flight: %d, the swapchain now has %d images\n", app.FramesInFlight, images)
+		if err := app.SetFramesInFlight(images); err != nil {
+			return fmt.Errorf("failed to reduce frames in flight: %w", err)
+		}
+	}
+
+	// Uniform rings keep the number of frames they were created with, so
+	// they must still cover every frame in flight.
+	for _, ring := range app.uniformRings {
+		if app.FramesInFlight > ring.Frames() {
+			return fmt.Errorf("%d frames in flight after recreating the swapchain, a uniform ring only has %d frames",
+				app.FramesInFlight, ring.Frames())
+		}
+	}
+
+	// Tell the application about the new extent.
+	if resized {
+		if err := app.hooks().OnResize(app.swapchain.Extent); err != nil {
+			return fmt.Errorf("OnResize hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (app *TriangleApplication) cleanup() {
+	// Setup may have failed part way, so only destroy what exists.
+	if app.device != vk.Device(vk.NullHandle) {
+		vk.DeviceWaitIdle(app.device)
+		if app.hooksSetup {
+			app.hooks().OnCleanup()
+		}
+		for _, ring := range append([]*UniformRing{}, app.uniformRings...) {
+			ring.Cleanup()
+		}
+		if app.pipeline != nil {
+			app.pipeline.Cleanup(app.device)
+		}
+		if app.swapchain != nil {
+			app.swapchain.Cleanup()
+		}
+
+		app.cleanupSyncObjects()
+		vk.DestroyCommandPool(app.device, app.graphicsCommandPool, nil)
+		if app.allocator != nil {
+			app.allocator.Cleanup()
+		}
+		if app.pipelineCache != nil {
+			if err := app.pipelineCache.Save(); err != nil {
+				fmt.Printf("Failed to save the pipeline cache: %v\n", err)
+			}
+			app.pipelineCache.Cleanup()
+		}
+		vk.DestroyDevice(app.device, nil)
+	}
+	if app.instance != vk.Instance(vk.NullHandle) {
+		if app.surface != vk.Surface(vk.NullHandle) {
+			vk.DestroySurface(app.instance, app.surface, nil)
+		}
+		vk.DestroyInstance(app.instance, nil)
+	}
+	if app.window != nil {
+		app.window.Destroy()
+	}
+	if !app.Headless {
+		glfw.Terminate()
+	}
+}
+
+func (app *TriangleApplication) Run() error {
+	if app.Headless {
+		return fmt.Errorf("headless applications have no main loop, use RenderHeadless")
+	}
+	defer app.cleanup()
+	if err := app.setup(); err != nil {
+		return err
+	}
+	return app.mainLoop()
+}
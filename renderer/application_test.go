@@ -0,0 +1,50 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestValidateFramesInFlight(t *testing.T) {
+	// A swapchain only needs a non-null surface to count as presenting.
+	var handle byte
+	presenting := &Swapchain{Images: make([]vk.Image, 3), surface: vk.Surface(unsafe.Pointer(&handle))}
+	offscreen := &Swapchain{Images: make([]vk.Image, 1)}
+
+	tests := []struct {
+		name      string
+		swapchain *Swapchain
+		rings     []uint
+		frames    uint
+		err       string
+	}{
+		{name: "before setup", frames: 5},
+		{name: "zero", frames: 0, err: "frames in flight must be at least 1"},
+		{name: "one per image", swapchain: presenting, frames: 3},
+		{name: "more than the images", swapchain: presenting, frames: 4, err: "4 frames in flight requested, the swapchain only has 3 images"},
+		{name: "offscreen", swapchain: offscreen, frames: 2},
+		{name: "within the rings", swapchain: presenting, rings: []uint{3, 2}, frames: 2},
+		{name: "more than a ring", swapchain: presenting, rings: []uint{3, 2}, frames: 3, err: "3 frames in flight requested, a uniform ring only has 2 frames"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := &TriangleApplication{swapchain: test.swapchain}
+			for _, frames := range test.rings {
+				app.uniformRings = append(app.uniformRings, &UniformRing{regions: NewRingRegions(256, 1, frames)})
+			}
+			err := app.validateFramesInFlight(test.frames)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,567 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// MemoryLayout is the rule for laying out the members of a uniform or
+// storage block.
+type MemoryLayout int
+
+const (
+	Std140 MemoryLayout = iota // Uniform buffers.
+	Std430                     // Storage buffers and push constants.
+)
+
+func (rule MemoryLayout) String() string {
+	switch rule {
+	case Std140:
+		return "std140"
+	case Std430:
+		return "std430"
+	}
+	return fmt.Sprintf("MemoryLayout(%d)", int(rule))
+}
+
+// Where a Go type goes in a block.
+type blockType struct {
+	Kind     TypeKind
+	Scalar   reflect.Kind // Component of scalars, vectors and matrices.
+	Width    uint32       // Component bytes.
+	Count    uint32       // Vector components, matrix columns or array length.
+	Rows     uint32       // Matrix rows.
+	RowMajor bool
+	Align    uint32
+	Size     uint32
+	Stride   uint32 // Array elements, or matrix columns (rows if RowMajor).
+	Elem     *blockType
+	Fields   []blockField
+}
+
+type blockField struct {
+	Name   string
+	Index  int // In the Go struct.
+	Offset uint32
+	Type   *blockType
+}
+
+// BlockLayout is where the fields of a Go struct go in a uniform or storage
+// block. Fields map to GLSL types by their Go type:
+//
+//	float32, float64, int32, uint32,    scalars; bools are 4 bytes
+//	int64, uint64, bool
+//	[N]T with N 2..4 and T a scalar     vecN
+//	[C][R]T with C and R 2..4 and T     matCxR, C columns of R rows
+//	float32 or float64
+//	other arrays                        arrays
+//	structs                             structs
+//	a slice as the last field           a runtime array
+//
+// Fields tagged `vk:"array"` are arrays even when they look like a vector or
+// matrix, `vk:"row_major"` stores matrices by rows, and fields named _ or
+// tagged `vk:"-"` are skipped. The padding comes from the rule, so the Go
+// struct doesn't need any.
+type BlockLayout struct {
+	Rule          MemoryLayout
+	Type          reflect.Type
+	Size          uint32 // Bytes before the runtime array, or of the whole block.
+	RuntimeStride uint32 // Bytes per runtime array element, 0 without one.
+
+	root *blockType
+}
+
+// NewBlockLayout lays out the struct type of block, a value or pointer.
+func NewBlockLayout(rule MemoryLayout, block interface{}) (*BlockLayout, error) {
+	t := reflect.TypeOf(block)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("block layout: %T is not a struct", block)
+	}
+
+	// Create the result object.
+	root, err := rule.structType(t, true)
+	if err != nil {
+		return nil, fmt.Errorf("block layout: %s %s: %w", rule, t, err)
+	}
+	layout := &BlockLayout{
+		Rule: rule,
+		Type: t,
+		Size: root.Size,
+		root: root,
+	}
+	if last := root.Fields[len(root.Fields)-1]; last.Type.Kind == TypeRuntimeArray {
+		layout.Size = last.Offset
+		layout.RuntimeStride = last.Type.Stride
+	}
+	return layout, nil
+}
+
+// Round v up to a multiple of alignment.
+func alignUp32(v, alignment uint32) uint32 {
+	return uint32(alignUp(vk.DeviceSize(v), vk.DeviceSize(alignment)))
+}
+
+// Vectors, arrays and structs in std140 are aligned like a vec4.
+func (rule MemoryLayout) roundAlign(align uint32) uint32 {
+	if rule == Std140 {
+		return alignUp32(align, 16)
+	}
+	return align
+}
+
+func (rule MemoryLayout) blockType(t reflect.Type, tag string) (*blockType, error) {
+	asArray, rowMajor := false, false
+	for _, opt := range strings.Split(tag, ",") {
+		switch strings.TrimSpace(opt) {
+		case "":
+		case "array":
+			asArray = true
+		case "row_major":
+			rowMajor = true
+		default:
+			return nil, fmt.Errorf("unknown tag option %q", opt)
+		}
+	}
+	return rule.newBlockType(t, asArray, rowMajor)
+}
+
+func (rule MemoryLayout) newBlockType(t reflect.Type, asArray, rowMajor bool) (*blockType, error) {
+	if kind, width := blockScalar(t.Kind()); width != 0 {
+		return &blockType{
+			Kind:   kind,
+			Scalar: t.Kind(),
+			Width:  width,
+			Align:  width,
+			Size:   width,
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Array:
+		if t.Len() == 0 {
+			return nil, fmt.Errorf("%s has no elements", t)
+		}
+		elem := t.Elem()
+		_, width := blockScalar(elem.Kind())
+
+		// Vectors.
+		if !asArray && width != 0 && t.Len() >= 2 && t.Len() <= 4 {
+			count := uint32(t.Len())
+			return &blockType{
+				Kind:   TypeVector,
+				Scalar: elem.Kind(),
+				Width:  width,
+				Count:  count,
+				Align:  vectorAlign(count, width),
+				Size:   count * width,
+			}, nil
+		}
+
+		// Matrices, stored as an array of column or row vectors.
+		if !asArray && elem.Kind() == reflect.Array && elem.Len() >= 2 && elem.Len() <= 4 &&
+			(elem.Elem().Kind() == reflect.Float32 || elem.Elem().Kind() == reflect.Float64) &&
+			t.Len() >= 2 && t.Len() <= 4 {
+			columns, rows := uint32(t.Len()), uint32(elem.Len())
+			_, width := blockScalar(elem.Elem().Kind())
+			vectors, count := columns, rows
+			if rowMajor {
+				vectors, count = rows, columns
+			}
+			stride := rule.roundAlign(vectorAlign(count, width))
+			return &blockType{
+				Kind:     TypeMatrix,
+				Scalar:   elem.Elem().Kind(),
+				Width:    width,
+				Count:    columns,
+				Rows:     rows,
+				RowMajor: rowMajor,
+				Align:    stride,
+				Size:     vectors * stride,
+				Stride:   stride,
+			}, nil
+		}
+
+		// Arrays.
+		elemType, err := rule.newBlockType(elem, false, rowMajor)
+		if err != nil {
+			return nil, err
+		}
+		align := rule.roundAlign(elemType.Align)
+		stride := alignUp32(elemType.Size, align)
+		return &blockType{
+			Kind:   TypeArray,
+			Count:  uint32(t.Len()),
+			Align:  align,
+			Size:   uint32(t.Len()) * stride,
+			Stride: stride,
+			Elem:   elemType,
+		}, nil
+
+	case reflect.Struct:
+		return rule.structType(t, false)
+
+	case reflect.Slice:
+		return nil, fmt.Errorf("%s: only the last field of the block can be a slice", t)
+	}
+	return nil, fmt.Errorf("%s has no GLSL type, use float32, float64, int32, uint32, int64, uint64, bool, arrays or structs", t)
+}
+
+func (rule MemoryLayout) structType(t reflect.Type, block bool) (*blockType, error) {
+	// Create the result object.
+	st := &blockType{
+		Kind:   TypeStruct,
+		Fields: make([]blockField, 0, t.NumField()),
+	}
+
+	// Find the fields that are laid out.
+	indices := make([]int, 0, t.NumField())
+	for h := 0; h < t.NumField(); h++ {
+		field := t.Field(h)
+		if field.Name == "_" || field.Tag.Get("vk") == "-" {
+			continue
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("%s is unexported", field.Name)
+		}
+		indices = append(indices, h)
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("%s has no fields", t)
+	}
+
+	// Place them in order.
+	offset := uint32(0)
+	for k, h := range indices {
+		field := t.Field(h)
+		var ft *blockType
+		var err error
+		if field.Type.Kind() == reflect.Slice && block && k == len(indices)-1 {
+			ft, err = rule.runtimeArrayType(field.Type, field.Tag.Get("vk"))
+		} else {
+			ft, err = rule.blockType(field.Type, field.Tag.Get("vk"))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		offset = alignUp32(offset, ft.Align)
+		st.Fields = append(st.Fields, blockField{
+			Name:   field.Name,
+			Index:  h,
+			Offset: offset,
+			Type:   ft,
+		})
+		offset += ft.Size
+		st.Align = MaxUint32(st.Align, ft.Align)
+	}
+	st.Align = rule.roundAlign(st.Align)
+	st.Size = alignUp32(offset, st.Align)
+	return st, nil
+}
+
+func (rule MemoryLayout) runtimeArrayType(t reflect.Type, tag string) (*blockType, error) {
+	elemType, err := rule.blockType(t.Elem(), tag)
+	if err != nil {
+		return nil, err
+	}
+	align := rule.roundAlign(elemType.Align)
+	return &blockType{
+		Kind:   TypeRuntimeArray,
+		Align:  align,
+		Stride: alignUp32(elemType.Size, align),
+		Elem:   elemType,
+	}, nil
+}
+
+// The reflected kind and byte width of a Go scalar, or 0 bytes.
+func blockScalar(kind reflect.Kind) (TypeKind, uint32) {
+	switch kind {
+	case reflect.Float32:
+		return TypeFloat, 4
+	case reflect.Float64:
+		return TypeFloat, 8
+	case reflect.Int32, reflect.Uint32:
+		return TypeInt, 4
+	case reflect.Int64, reflect.Uint64:
+		return TypeInt, 8
+	case reflect.Bool:
+		return TypeBool, 4
+	}
+	return TypeUnknown, 0
+}
+
+// vec2s are aligned to two components, vec3s and vec4s to four.
+func vectorAlign(count, width uint32) uint32 {
+	if count == 2 {
+		return 2 * width
+	}
+	return 4 * width
+}
+
+// GLSL-like spelling of the type for messages.
+func (bt *blockType) String() string {
+	scalar := func() string {
+		switch bt.Scalar {
+		case reflect.Bool:
+			return "bool"
+		case reflect.Int32, reflect.Int64:
+			return fmt.Sprintf("int%d", bt.Width*8)
+		case reflect.Uint32, reflect.Uint64:
+			return fmt.Sprintf("uint%d", bt.Width*8)
+		}
+		return fmt.Sprintf("float%d", bt.Width*8)
+	}
+	switch bt.Kind {
+	case TypeVector:
+		return fmt.Sprintf("%s vec%d", scalar(), bt.Count)
+	case TypeMatrix:
+		return fmt.Sprintf("%s mat%dx%d", scalar(), bt.Count, bt.Rows)
+	case TypeArray:
+		return fmt.Sprintf("%s[%d]", bt.Elem, bt.Count)
+	case TypeRuntimeArray:
+		return fmt.Sprintf("%s[]", bt.Elem)
+	case TypeStruct:
+		return fmt.Sprintf("struct with %d members", len(bt.Fields))
+	}
+	return scalar()
+}
+
+// Encode lays out a value of the block's type, a value or pointer. Each
+// element of the runtime array adds RuntimeStride bytes after Size.
+func (layout *BlockLayout) Encode(block interface{}) ([]byte, error) {
+	v := reflect.ValueOf(block)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != layout.Type {
+		return nil, fmt.Errorf("block layout: encoding %T with the layout of %s", block, layout.Type)
+	}
+	size := layout.Size
+	if last := layout.root.Fields[len(layout.root.Fields)-1]; last.Type.Kind == TypeRuntimeArray {
+		size += uint32(v.Field(last.Index).Len()) * layout.RuntimeStride
+	}
+	data := make([]byte, size)
+	layout.root.encode(data, v)
+	return data, nil
+}
+
+func (bt *blockType) encode(data []byte, v reflect.Value) {
+	switch bt.Kind {
+	case TypeVector:
+		for h := 0; h < int(bt.Count); h++ {
+			putBlockScalar(data[uint32(h)*bt.Width:], v.Index(h))
+		}
+	case TypeMatrix:
+		for c := uint32(0); c < bt.Count; c++ {
+			for r := uint32(0); r < bt.Rows; r++ {
+				putBlockScalar(data[bt.componentOffset(c, r):], v.Index(int(c)).Index(int(r)))
+			}
+		}
+	case TypeArray, TypeRuntimeArray:
+		for h := 0; h < v.Len(); h++ {
+			bt.Elem.encode(data[uint32(h)*bt.Stride:], v.Index(h))
+		}
+	case TypeStruct:
+		for _, f := range bt.Fields {
+			f.Type.encode(data[f.Offset:], v.Field(f.Index))
+		}
+	default:
+		putBlockScalar(data, v)
+	}
+}
+
+// Decode reads the layout back into block, a pointer. The runtime array is
+// resized to the number of whole elements after Size.
+func (layout *BlockLayout) Decode(data []byte, block interface{}) error {
+	v := reflect.ValueOf(block)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Type() != layout.Type {
+		return fmt.Errorf("block layout: decoding into %T, it must be a *%s", block, layout.Type)
+	}
+	v = v.Elem()
+	if uint32(len(data)) < layout.Size {
+		return fmt.Errorf("block layout: %d bytes is less than the %d byte %s block", len(data), layout.Size, layout.Type)
+	}
+	if last := layout.root.Fields[len(layout.root.Fields)-1]; last.Type.Kind == TypeRuntimeArray {
+		n := (uint32(len(data)) - layout.Size) / layout.RuntimeStride
+		v.Field(last.Index).Set(reflect.MakeSlice(v.Field(last.Index).Type(), int(n), int(n)))
+	}
+	layout.root.decode(data, v)
+	return nil
+}
+
+func (bt *blockType) decode(data []byte, v reflect.Value) {
+	switch bt.Kind {
+	case TypeVector:
+		for h := 0; h < int(bt.Count); h++ {
+			getBlockScalar(data[uint32(h)*bt.Width:], v.Index(h))
+		}
+	case TypeMatrix:
+		for c := uint32(0); c < bt.Count; c++ {
+			for r := uint32(0); r < bt.Rows; r++ {
+				getBlockScalar(data[bt.componentOffset(c, r):], v.Index(int(c)).Index(int(r)))
+			}
+		}
+	case TypeArray, TypeRuntimeArray:
+		for h := 0; h < v.Len(); h++ {
+			bt.Elem.decode(data[uint32(h)*bt.Stride:], v.Index(h))
+		}
+	case TypeStruct:
+		for _, f := range bt.Fields {
+			f.Type.decode(data[f.Offset:], v.Field(f.Index))
+		}
+	default:
+		getBlockScalar(data, v)
+	}
+}
+
+// Offset of a matrix component from the start of the matrix.
+func (bt *blockType) componentOffset(column, row uint32) uint32 {
+	if bt.RowMajor {
+		return row*bt.Stride + column*bt.Width
+	}
+	return column*bt.Stride + row*bt.Width
+}
+
+func putBlockScalar(data []byte, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Float32:
+		binary.LittleEndian.PutUint32(data, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		binary.LittleEndian.PutUint64(data, math.Float64bits(v.Float()))
+	case reflect.Int32:
+		binary.LittleEndian.PutUint32(data, uint32(v.Int()))
+	case reflect.Int64:
+		binary.LittleEndian.PutUint64(data, uint64(v.Int()))
+	case reflect.Uint32:
+		binary.LittleEndian.PutUint32(data, uint32(v.Uint()))
+	case reflect.Uint64:
+		binary.LittleEndian.PutUint64(data, v.Uint())
+	case reflect.Bool:
+		if v.Bool() {
+			binary.LittleEndian.PutUint32(data, 1)
+		}
+	}
+}
+
+func getBlockScalar(data []byte, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(data)))
+	case reflect.Int32:
+		v.SetInt(int64(int32(binary.LittleEndian.Uint32(data))))
+	case reflect.Int64:
+		v.SetInt(int64(binary.LittleEndian.Uint64(data)))
+	case reflect.Uint32:
+		v.SetUint(uint64(binary.LittleEndian.Uint32(data)))
+	case reflect.Uint64:
+		v.SetUint(binary.LittleEndian.Uint64(data))
+	case reflect.Bool:
+		v.SetBool(binary.LittleEndian.Uint32(data) != 0)
+	}
+}
+
+// Check compares the layout with a block reflected from a shader, such as
+// the Type of a DescriptorBinding. The members must have the same types, and
+// the Offset, ArrayStride and MatrixStride decorations must match.
+func (layout *BlockLayout) Check(block *Type) error {
+	if block == nil || block.Kind != TypeStruct {
+		return fmt.Errorf("block layout: %s is not a shader block", block)
+	}
+	return layout.root.check(block, Decorations{}, OrDefault(block.Name, "block"))
+}
+
+func (bt *blockType) check(t *Type, decs Decorations, path string) error {
+	mismatch := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...))
+	}
+
+	// Scalars, also of vectors and matrices.
+	scalar := func(component *Type) bool {
+		switch {
+		case bt.Scalar == reflect.Bool:
+			// Bools in blocks are usually 32 bit ints in SPIR-V.
+			return component.Kind == TypeBool || (component.Kind == TypeInt && component.Width == 32)
+		case component.Kind != TypeFloat && component.Kind != TypeInt:
+			return false
+		}
+		kind, width := blockScalar(bt.Scalar)
+		signed := bt.Scalar == reflect.Int32 || bt.Scalar == reflect.Int64
+		return component.Kind == kind && component.Width == width*8 && (kind != TypeInt || component.Signed == signed)
+	}
+
+	switch bt.Kind {
+	case TypeVector:
+		if t.Kind != TypeVector || t.Count != bt.Count || !scalar(t.Elem) {
+			return mismatch("%s in the shader, %s in Go", t, bt)
+		}
+	case TypeMatrix:
+		if t.Kind != TypeMatrix || t.Count != bt.Count || t.Elem.Count != bt.Rows || !scalar(t.Elem.Elem) {
+			return mismatch("%s in the shader, %s in Go", t, bt)
+		}
+		if decs.MatrixStride.IsSet() && decs.MatrixStride.Val() != bt.Stride {
+			return mismatch("matrix stride %d in the shader, %d in Go", decs.MatrixStride.Val(), bt.Stride)
+		}
+		if decs.RowMajor != bt.RowMajor {
+			return mismatch("row major is %t in the shader, %t in Go", decs.RowMajor, bt.RowMajor)
+		}
+	case TypeArray, TypeRuntimeArray:
+		if t.Kind != bt.Kind || (bt.Kind == TypeArray && t.Count != bt.Count) {
+			return mismatch("%s in the shader, %s in Go", t, bt)
+		}
+		if t.Decorations.ArrayStride.IsSet() && t.Decorations.ArrayStride.Val() != bt.Stride {
+			return mismatch("array stride %d in the shader, %d in Go", t.Decorations.ArrayStride.Val(), bt.Stride)
+		}
+		return bt.Elem.check(t.Elem, decs, path+"[]")
+	case TypeStruct:
+		if t.Kind != TypeStruct || len(t.Members) != len(bt.Fields) {
+			return mismatch("%s in the shader, %s in Go", t, bt)
+		}
+		for k, f := range bt.Fields {
+			memberDecs := t.MemberDecorations[k]
+			memberPath := path + "." + OrDefault(t.MemberNames[k], f.Name)
+			if memberDecs.Offset.IsSet() && memberDecs.Offset.Val() != f.Offset {
+				return fmt.Errorf("%s: offset %d in the shader, %d in Go", memberPath, memberDecs.Offset.Val(), f.Offset)
+			}
+			if err := f.Type.check(t.Members[k], memberDecs, memberPath); err != nil {
+				return err
+			}
+		}
+	default:
+		if !scalar(t) {
+			return mismatch("%s in the shader, %s in Go", t, bt)
+		}
+	}
+	return nil
+}
+
+// BindingBlockLayout lays out block for a uniform or storage buffer binding,
+// std140 for uniform buffers and std430 for storage buffers, and checks it
+// against the block the shaders declare.
+func BindingBlockLayout(binding DescriptorBinding, block interface{}) (*BlockLayout, error) {
+	var rule MemoryLayout
+	switch binding.DescriptorType {
+	case vk.DescriptorTypeUniformBuffer, vk.DescriptorTypeUniformBufferDynamic:
+		rule = Std140
+	case vk.DescriptorTypeStorageBuffer, vk.DescriptorTypeStorageBufferDynamic:
+		rule = Std430
+	default:
+		return nil, fmt.Errorf("binding %s is not a uniform or storage buffer", binding)
+	}
+	layout, err := NewBlockLayout(rule, block)
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.Check(binding.Type); err != nil {
+		return nil, fmt.Errorf("binding %s: %w", binding, err)
+	}
+	return layout, nil
+}
@@ -0,0 +1,297 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type blockScalarArray struct {
+	A [5]float32
+	B float32
+}
+
+type blockVec3Float struct {
+	V [3]float32
+	F float32
+}
+
+type blockMat3 struct {
+	F float32
+	M [3][3]float32
+	G float32
+}
+
+type blockMat3RowMajor struct {
+	F float32
+	M [3][2]float32 `vk:"row_major"`
+	G float32
+}
+
+type blockMat2 struct {
+	F float32
+	M [2][2]float32
+	G float32
+}
+
+type blockDVec3 struct {
+	F float32
+	D [3]float64
+	G float32
+}
+
+type blockBoolArray struct {
+	B [3]bool `vk:"array"`
+	F float32
+}
+
+type blockInner struct {
+	X float32
+}
+
+type blockNested struct {
+	S blockInner
+	Y float32
+}
+
+type blockRuntime struct {
+	Count uint32
+	Items []blockVec3Float
+}
+
+// The fields of a layout as offsets, and strides where they have one.
+func blockOffsets(layout *BlockLayout) (offsets, strides []uint32) {
+	for _, f := range layout.root.Fields {
+		offsets = append(offsets, f.Offset)
+		strides = append(strides, f.Type.Stride)
+	}
+	return offsets, strides
+}
+
+func TestBlockLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          MemoryLayout
+		block         interface{}
+		offsets       []uint32
+		strides       []uint32
+		size          uint32
+		runtimeStride uint32
+	}{
+		{"std140 scalar array", Std140, blockScalarArray{}, []uint32{0, 80}, []uint32{16, 0}, 96, 0},
+		{"std430 scalar array", Std430, blockScalarArray{}, []uint32{0, 20}, []uint32{4, 0}, 24, 0},
+		{"std140 vec3 float", Std140, blockVec3Float{}, []uint32{0, 12}, []uint32{0, 0}, 16, 0},
+		{"std430 vec3 float", Std430, blockVec3Float{}, []uint32{0, 12}, []uint32{0, 0}, 16, 0},
+		{"std140 mat3", Std140, blockMat3{}, []uint32{0, 16, 64}, []uint32{0, 16, 0}, 80, 0},
+		{"std430 mat3", Std430, blockMat3{}, []uint32{0, 16, 64}, []uint32{0, 16, 0}, 80, 0},
+		{"std140 mat3x2 row major", Std140, blockMat3RowMajor{}, []uint32{0, 16, 48}, []uint32{0, 16, 0}, 64, 0},
+		{"std430 mat3x2 row major", Std430, blockMat3RowMajor{}, []uint32{0, 16, 48}, []uint32{0, 16, 0}, 64, 0},
+		{"std140 mat2", Std140, blockMat2{}, []uint32{0, 16, 48}, []uint32{0, 16, 0}, 64, 0},
+		{"std430 mat2", Std430, blockMat2{}, []uint32{0, 8, 24}, []uint32{0, 8, 0}, 32, 0},
+		{"std140 dvec3", Std140, blockDVec3{}, []uint32{0, 32, 56}, []uint32{0, 0, 0}, 64, 0},
+		{"std430 dvec3", Std430, blockDVec3{}, []uint32{0, 32, 56}, []uint32{0, 0, 0}, 64, 0},
+		{"std140 bool array", Std140, blockBoolArray{}, []uint32{0, 48}, []uint32{16, 0}, 64, 0},
+		{"std430 bool array", Std430, blockBoolArray{}, []uint32{0, 12}, []uint32{4, 0}, 16, 0},
+		{"std140 nested struct", Std140, blockNested{}, []uint32{0, 16}, []uint32{0, 0}, 32, 0},
+		{"std430 nested struct", Std430, blockNested{}, []uint32{0, 4}, []uint32{0, 0}, 8, 0},
+		{"std140 runtime array", Std140, blockRuntime{}, []uint32{0, 16}, []uint32{0, 16}, 16, 16},
+		{"std430 runtime array", Std430, blockRuntime{}, []uint32{0, 16}, []uint32{0, 16}, 16, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := NewBlockLayout(tt.rule, tt.block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offsets, strides := blockOffsets(layout)
+			if !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("offsets = %v, want %v", offsets, tt.offsets)
+			}
+			if !reflect.DeepEqual(strides, tt.strides) {
+				t.Errorf("strides = %v, want %v", strides, tt.strides)
+			}
+			if layout.Size != tt.size {
+				t.Errorf("Size = %d, want %d", layout.Size, tt.size)
+			}
+			if layout.RuntimeStride != tt.runtimeStride {
+				t.Errorf("RuntimeStride = %d, want %d", layout.RuntimeStride, tt.runtimeStride)
+			}
+		})
+	}
+}
+
+func TestBlockLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		block interface{}
+		want  string
+	}{
+		{"not a struct", 1.0, "is not a struct"},
+		{"go int", struct{ X int }{}, "has no GLSL type"},
+		{"unexported", struct{ x float32 }{}, "x is unexported"},
+		{"slice not last", struct {
+			S []float32
+			Y float32
+		}{}, "only the last field"},
+		{"empty array", struct{ A [0]float32 }{}, "has no elements"},
+		{"bad tag", struct {
+			A float32 `vk:"bogus"`
+		}{}, `unknown tag option "bogus"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBlockLayout(Std140, tt.block)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewBlockLayout() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+type blockRoundTrip struct {
+	S  blockInner
+	Y  float32
+	V  [3]float32
+	M  [2][2]float32
+	RM [3][2]float32 `vk:"row_major"`
+	B  bool
+	I  [2]int32
+	D  float64
+	U  [4]uint32 `vk:"array"`
+	L  []blockVec3Float
+}
+
+func TestBlockLayoutRoundTrip(t *testing.T) {
+	in := blockRoundTrip{
+		S:  blockInner{X: 1},
+		Y:  2,
+		V:  [3]float32{3, 4, 5},
+		M:  [2][2]float32{{6, 7}, {8, 9}},
+		RM: [3][2]float32{{1, 2}, {3, 4}, {5, 6}},
+		B:  true,
+		I:  [2]int32{-1, 2},
+		D:  -0.5,
+		U:  [4]uint32{1, 2, 3, 0xffffffff},
+		L: []blockVec3Float{
+			{V: [3]float32{1, 2, 3}, F: 4},
+			{V: [3]float32{5, 6, 7}, F: 8},
+		},
+	}
+	for _, rule := range []MemoryLayout{Std140, Std430} {
+		t.Run(rule.String(), func(t *testing.T) {
+			layout, err := NewBlockLayout(rule, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := layout.Encode(&in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := layout.Size + 2*layout.RuntimeStride; uint32(len(data)) != want {
+				t.Errorf("len(Encode()) = %d, want %d", len(data), want)
+			}
+			var out blockRoundTrip
+			if err := layout.Decode(data, &out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(in, out) {
+				t.Errorf("Decode(Encode()) = %+v, want %+v", out, in)
+			}
+		})
+	}
+}
+
+func TestBlockLayoutRowMajorEncoding(t *testing.T) {
+	layout, err := NewBlockLayout(Std140, blockMat3RowMajor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := layout.Encode(blockMat3RowMajor{M: [3][2]float32{{1, 2}, {3, 4}, {5, 6}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two rows of three columns, 16 bytes apart.
+	var got [2][3]float32
+	for r := range got {
+		for c := range got[r] {
+			got[r][c] = math.Float32frombits(binary.LittleEndian.Uint32(data[16+r*16+c*4:]))
+		}
+	}
+	if want := [2][3]float32{{1, 3, 5}, {2, 4, 6}}; got != want {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+// The std140 shader block matching blockMat3 with a scalar array after it.
+func blockShaderType() *Type {
+	f32 := &Type{Kind: TypeFloat, Width: 32}
+	vec3 := &Type{Kind: TypeVector, Count: 3, Elem: f32}
+	mat3 := &Type{Kind: TypeMatrix, Count: 3, Elem: vec3}
+	arr := &Type{Kind: TypeArray, Count: 5, Elem: f32}
+	arr.Decorations.ArrayStride.Set(16)
+	block := &Type{
+		Kind:              TypeStruct,
+		Name:              "Block",
+		Members:           []*Type{f32, mat3, arr},
+		MemberNames:       []string{"f", "m", "a"},
+		MemberDecorations: make([]Decorations, 3),
+	}
+	block.MemberDecorations[0].Offset.Set(0)
+	block.MemberDecorations[1].Offset.Set(16)
+	block.MemberDecorations[1].MatrixStride.Set(16)
+	block.MemberDecorations[2].Offset.Set(64)
+	return block
+}
+
+type blockChecked struct {
+	F float32
+	M [3][3]float32
+	A [5]float32
+}
+
+type blockCheckedRowMajor struct {
+	F float32
+	M [3][3]float32 `vk:"row_major"`
+	A [5]float32
+}
+
+func TestBlockLayoutCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   MemoryLayout
+		block  interface{}
+		modify func(*Type)
+		want   string
+	}{
+		{"match", Std140, blockChecked{}, func(*Type) {}, ""},
+		{"offset", Std140, blockChecked{}, func(b *Type) {
+			b.MemberDecorations[1].Offset.Set(32)
+		}, "Block.m: offset 32 in the shader, 16 in Go"},
+		{"array stride", Std430, blockChecked{}, func(*Type) {}, "Block.a: array stride 16 in the shader, 4 in Go"},
+		{"matrix stride", Std140, blockChecked{}, func(b *Type) {
+			b.MemberDecorations[1].MatrixStride.Set(12)
+		}, "Block.m: matrix stride 12 in the shader, 16 in Go"},
+		{"row major", Std140, blockCheckedRowMajor{}, func(*Type) {}, "Block.m: row major is false in the shader, true in Go"},
+		{"type", Std140, blockVec3Float{}, func(*Type) {}, "Block: struct Block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := NewBlockLayout(tt.rule, tt.block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shader := blockShaderType()
+			tt.modify(shader)
+			err = layout.Check(shader)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Check() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Check() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
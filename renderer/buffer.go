@@ -0,0 +1,245 @@
+package renderer
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Buffer is a buffer and the memory it is bound to.
+type Buffer struct {
+	Handle     vk.Buffer
+	Size       vk.DeviceSize
+	Allocation MemoryAllocation
+
+	allocator *Allocator
+}
+
+// NewBuffer creates a buffer with memory from the allocator.
+func NewBuffer(allocator *Allocator, size vk.DeviceSize, usage vk.BufferUsageFlags, required, preferred vk.MemoryPropertyFlags) (*Buffer, error) {
+	// Create the info object.
+	bufferInfo := vk.BufferCreateInfo{
+		SType:       vk.StructureTypeBufferCreateInfo,
+		Size:        size,
+		Usage:       usage,
+		SharingMode: vk.SharingModeExclusive,
+	}
+
+	// Create the result object.
+	buffer := &Buffer{
+		Size:      size,
+		allocator: allocator,
+	}
+
+	// Create the buffer.
+	var err error
+	buffer.Handle, buffer.Allocation, err = allocator.AllocateBuffer(bufferInfo, required, preferred)
+	if err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// NewDeviceLocalBuffer creates a buffer in device local memory holding data.
+// The data is copied into a host visible staging buffer, and from there into
+// the buffer on the graphics queue.
+func NewDeviceLocalBuffer(app *TriangleApplication, data []byte, usage vk.BufferUsageFlags) (*Buffer, error) {
+	size := vk.DeviceSize(len(data))
+	if size == 0 {
+		return nil, fmt.Errorf("buffer: no data to upload")
+	}
+
+	// Create the staging buffer.
+	staging, err := NewBuffer(app.allocator,
+		size,
+		vk.BufferUsageFlags(vk.BufferUsageTransferSrcBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit|vk.MemoryPropertyHostCoherentBit),
+		0)
+	if err != nil {
+		return nil, err
+	}
+	defer staging.Cleanup()
+
+	// Fill the staging buffer.
+	if err := staging.Write(0, data); err != nil {
+		return nil, err
+	}
+
+	// Create the device local buffer.
+	buffer, err := NewBuffer(app.allocator,
+		size,
+		usage|vk.BufferUsageFlags(vk.BufferUsageTransferDstBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit),
+		0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the staging buffer into it.
+	err = submitOneTime(app.device, app.graphicsCommandPool, app.graphicsQueue, func(cmd vk.CommandBuffer) {
+		vk.CmdCopyBuffer(cmd, staging.Handle, buffer.Handle, 1, []vk.BufferCopy{
+			vk.BufferCopy{Size: size},
+		})
+	})
+	if err != nil {
+		buffer.Cleanup()
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// Write copies data into a host visible buffer at offset.
+func (buffer *Buffer) Write(offset vk.DeviceSize, data []byte) error {
+	if offset+vk.DeviceSize(len(data)) > buffer.Size {
+		return fmt.Errorf("buffer: writing %d bytes at %d overflows the %d byte buffer", len(data), offset, buffer.Size)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	ptr, err := buffer.allocator.Map(buffer.Allocation)
+	if err != nil {
+		return err
+	}
+	copy(unsafe.Slice((*byte)(ptr), buffer.Size)[offset:], data)
+	return nil
+}
+
+// Destroy the buffer and free its memory.
+func (buffer *Buffer) Cleanup() {
+	vk.DestroyBuffer(buffer.allocator.device, buffer.Handle, nil)
+	buffer.allocator.Free(buffer.Allocation)
+	buffer.Handle = vk.Buffer(vk.NullHandle)
+	buffer.Allocation = MemoryAllocation{}
+}
+
+// VertexBuffer is a device local buffer of vertices.
+type VertexBuffer struct {
+	*Buffer
+	Count  uint32 // Number of vertices.
+	Stride uint32 // Bytes per vertex.
+}
+
+// NewVertexBuffer uploads a slice of vertices. The vertex type must be plain
+// data: numbers, arrays and structs of them, laid out the way the pipeline's
+// vertex input expects.
+func NewVertexBuffer(app *TriangleApplication, vertices interface{}) (*VertexBuffer, error) {
+	data, stride, count, err := SliceBytes(vertices)
+	if err != nil {
+		return nil, fmt.Errorf("vertex buffer: %w", err)
+	}
+	buffer, err := NewDeviceLocalBuffer(app, data, vk.BufferUsageFlags(vk.BufferUsageVertexBufferBit))
+	if err != nil {
+		return nil, err
+	}
+	return &VertexBuffer{
+		Buffer: buffer,
+		Count:  count,
+		Stride: stride,
+	}, nil
+}
+
+// IndexBuffer is a device local buffer of 16 or 32 bit indices.
+type IndexBuffer struct {
+	*Buffer
+	Count     uint32 // Number of indices.
+	IndexType vk.IndexType
+}
+
+// NewIndexBuffer16 uploads 16 bit indices.
+func NewIndexBuffer16(app *TriangleApplication, indices []uint16) (*IndexBuffer, error) {
+	return newIndexBuffer(app, indices, vk.IndexTypeUint16)
+}
+
+// NewIndexBuffer32 uploads 32 bit indices.
+func NewIndexBuffer32(app *TriangleApplication, indices []uint32) (*IndexBuffer, error) {
+	return newIndexBuffer(app, indices, vk.IndexTypeUint32)
+}
+
+func newIndexBuffer(app *TriangleApplication, indices interface{}, indexType vk.IndexType) (*IndexBuffer, error) {
+	data, _, count, err := SliceBytes(indices)
+	if err != nil {
+		return nil, fmt.Errorf("index buffer: %w", err)
+	}
+	buffer, err := NewDeviceLocalBuffer(app, data, vk.BufferUsageFlags(vk.BufferUsageIndexBufferBit))
+	if err != nil {
+		return nil, err
+	}
+	return &IndexBuffer{
+		Buffer:    buffer,
+		Count:     count,
+		IndexType: indexType,
+	}, nil
+}
+
+// Mesh is geometry that can be drawn with one call. Without indices the
+// vertices are drawn in order.
+type Mesh struct {
+	Vertices *VertexBuffer
+	Indices  *IndexBuffer
+}
+
+// Draw binds the vertex buffer to binding 0 and the index buffer, and draws
+// the mesh. It is called while recording, such as from OnRecord.
+func (mesh Mesh) Draw(cmd vk.CommandBuffer, instances uint32) {
+	vk.CmdBindVertexBuffers(cmd, 0, 1, []vk.Buffer{mesh.Vertices.Handle}, []vk.DeviceSize{0})
+	if mesh.Indices == nil {
+		vk.CmdDraw(cmd, mesh.Vertices.Count, instances, 0, 0)
+		return
+	}
+	vk.CmdBindIndexBuffer(cmd, mesh.Indices.Handle, 0, mesh.Indices.IndexType)
+	vk.CmdDrawIndexed(cmd, mesh.Indices.Count, instances, 0, 0, 0)
+}
+
+// Destroy the buffers.
+func (mesh Mesh) Cleanup() {
+	if mesh.Vertices != nil {
+		mesh.Vertices.Cleanup()
+	}
+	if mesh.Indices != nil {
+		mesh.Indices.Cleanup()
+	}
+}
+
+// SliceBytes returns a copy of the bytes of a slice of plain data, with the
+// size of an element and the number of elements.
+func SliceBytes(slice interface{}) ([]byte, uint32, uint32, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, 0, 0, fmt.Errorf("%T is not a slice", slice)
+	}
+	elem := v.Type().Elem()
+	if !isPlainData(elem) {
+		return nil, 0, 0, fmt.Errorf("%s holds pointers or references, only numbers, arrays and structs can be uploaded", elem)
+	}
+	stride := elem.Size()
+	if v.Len() == 0 || stride == 0 {
+		return nil, uint32(stride), 0, nil
+	}
+	data := make([]byte, uintptr(v.Len())*stride)
+	copy(data, unsafe.Slice((*byte)(unsafe.Pointer(v.Pointer())), len(data)))
+	return data, uint32(stride), uint32(v.Len()), nil
+}
+
+// Reports if values of the type hold no pointers, so their bytes are
+// meaningful to the device.
+func isPlainData(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Array:
+		return isPlainData(t.Elem())
+	case reflect.Struct:
+		for h := 0; h < t.NumField(); h++ {
+			if !isPlainData(t.Field(h).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
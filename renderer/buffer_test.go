@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSliceBytes(t *testing.T) {
+	type vertex struct {
+		Pos   [2]float32
+		Color [3]uint8
+		Flag  bool
+	}
+	tests := []struct {
+		name   string
+		slice  interface{}
+		data   []byte
+		stride uint32
+		count  uint32
+		err    string
+	}{
+		{name: "bytes", slice: []uint8{1, 2, 3}, data: []byte{1, 2, 3}, stride: 1, count: 3},
+		{name: "uint16", slice: []uint16{0x0201, 0x0403}, data: []byte{1, 2, 3, 4}, stride: 2, count: 2},
+		{name: "arrays", slice: [][2]int8{{1, -1}, {2, -2}}, data: []byte{1, 0xff, 2, 0xfe}, stride: 2, count: 2},
+		{
+			name:   "structs",
+			slice:  []vertex{{Color: [3]uint8{1, 2, 3}}, {Color: [3]uint8{4, 5, 6}, Flag: true}},
+			data:   []byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4, 5, 6, 1},
+			stride: 12,
+			count:  2,
+		},
+		{name: "empty", slice: []uint32{}, stride: 4, count: 0},
+		{name: "nil", slice: []float32(nil), stride: 4, count: 0},
+		{name: "empty structs", slice: []struct{}{{}, {}}, stride: 0, count: 0},
+		{name: "not a slice", slice: [2]uint16{}, err: "[2]uint16 is not a slice"},
+		{name: "nil interface", slice: nil, err: "<nil> is not a slice"},
+		{name: "pointers", slice: []*uint32{nil}, err: "*uint32 holds pointers or references"},
+		{name: "strings", slice: []string{"a"}, err: "string holds pointers or references"},
+		{name: "int", slice: []int{1}, err: "int holds pointers or references"},
+		{name: "struct with a slice", slice: []struct{ A []byte }{{}}, err: "struct { A []uint8 } holds pointers or references"},
+		{name: "array of pointers", slice: [][2]*byte{{}}, err: "[2]*uint8 holds pointers or references"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, stride, count, err := SliceBytes(test.slice)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, test.data) || stride != test.stride || count != test.count {
+				t.Errorf("got %v, stride %d, count %d, expected %v, stride %d, count %d",
+					data, stride, count, test.data, test.stride, test.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Depth buffer format preference lists, most preferred first.
+var (
+	// Depth only. Falls back to formats with stencil, which every device
+	// has at least one of.
+	DepthFormats = []vk.Format{
+		vk.FormatD32Sfloat,
+		vk.FormatD32SfloatS8Uint,
+		vk.FormatD24UnormS8Uint,
+		vk.FormatD16Unorm,
+	}
+
+	// Depth and stencil.
+	DepthStencilFormats = []vk.Format{
+		vk.FormatD32SfloatS8Uint,
+		vk.FormatD24UnormS8Uint,
+		vk.FormatD16UnormS8Uint,
+	}
+)
+
+// Reports if the depth format has a stencil component.
+func HasStencilComponent(format vk.Format) bool {
+	switch format {
+	case vk.FormatD32SfloatS8Uint, vk.FormatD24UnormS8Uint, vk.FormatD16UnormS8Uint:
+		return true
+	}
+	return false
+}
+
+// The image aspects of a depth format.
+func depthAspectMask(format vk.Format) vk.ImageAspectFlags {
+	aspect := vk.ImageAspectFlags(vk.ImageAspectDepthBit)
+	if HasStencilComponent(format) {
+		aspect |= vk.ImageAspectFlags(vk.ImageAspectStencilBit)
+	}
+	return aspect
+}
+
+// FindDepthFormat returns the first of the formats the device can use as a
+// depth attachment.
+func (phyDev PhysicalDevice) FindDepthFormat(candidates []vk.Format) (vk.Format, error) {
+	format, ok := phyDev.FindSupportedFormat(candidates,
+		vk.ImageTilingOptimal,
+		vk.FormatFeatureFlags(vk.FormatFeatureDepthStencilAttachmentBit))
+	if !ok {
+		return format, fmt.Errorf("none of the depth formats %v can be used as a depth attachment", candidates)
+	}
+	return format, nil
+}
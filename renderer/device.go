@@ -0,0 +1,248 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Physical Device
+type PhysicalDevice struct {
+	Handle                vk.PhysicalDevice
+	Properties            vk.PhysicalDeviceProperties
+	Features              vk.PhysicalDeviceFeatures
+	LayerProperties       []vk.LayerProperties
+	ExtensionProperties   []vk.ExtensionProperties
+	QueueFamilyProperties []vk.QueueFamilyProperties
+	MemoryProperties      vk.PhysicalDeviceMemoryProperties
+}
+
+func EnumeratePhysicalDevices(instance vk.Instance) []PhysicalDevice {
+	// 2-call enumerate the devices
+	var count uint32
+	vk.EnumeratePhysicalDevices(instance, &count, nil)
+	list := make([]vk.PhysicalDevice, count)
+	vk.EnumeratePhysicalDevices(instance, &count, list)
+
+	// Loop over each device and get extra data.
+	physicalDevices := make([]PhysicalDevice, len(list))
+	for k, phyDev := range list {
+		// Store the Handle.
+		physicalDevices[k].Handle = phyDev
+
+		// Get the physical device properties.
+		vk.GetPhysicalDeviceProperties(phyDev, &physicalDevices[k].Properties)
+
+		// Get the physical device features.
+		vk.GetPhysicalDeviceFeatures(phyDev, &physicalDevices[k].Features)
+
+		// 2-call enumerate the layer properties.
+		vk.EnumerateDeviceLayerProperties(phyDev, &count, nil)
+		physicalDevices[k].LayerProperties = make([]vk.LayerProperties, count)
+		vk.EnumerateDeviceLayerProperties(phyDev, &count, physicalDevices[k].LayerProperties)
+
+		// 2-call enumerate the extension properties.
+		vk.EnumerateDeviceExtensionProperties(phyDev, "", &count, nil)
+		physicalDevices[k].ExtensionProperties = make([]vk.ExtensionProperties, count)
+		vk.EnumerateDeviceExtensionProperties(phyDev, "", &count, physicalDevices[k].ExtensionProperties)
+
+		// 2-call enumerate the queue family properties.
+		vk.GetPhysicalDeviceQueueFamilyProperties(phyDev, &count, nil)
+		physicalDevices[k].QueueFamilyProperties = make([]vk.QueueFamilyProperties, count)
+		vk.GetPhysicalDeviceQueueFamilyProperties(phyDev, &count, physicalDevices[k].QueueFamilyProperties)
+
+		// Get the physical device memory properties.
+		vk.GetPhysicalDeviceMemoryProperties(phyDev, &physicalDevices[k].MemoryProperties)
+
+		// Dereference the data.
+		physicalDevices[k].Properties.Deref()
+		physicalDevices[k].Properties.Limits.Deref()
+		physicalDevices[k].Features.Deref()
+		for h := 0; h < len(physicalDevices[k].LayerProperties); h++ {
+			physicalDevices[k].LayerProperties[h].Deref()
+		}
+		for h := 0; h < len(physicalDevices[k].ExtensionProperties); h++ {
+			physicalDevices[k].ExtensionProperties[h].Deref()
+		}
+		for h := 0; h < len(physicalDevices[k].QueueFamilyProperties); h++ {
+			physicalDevices[k].QueueFamilyProperties[h].Deref()
+		}
+		physicalDevices[k].MemoryProperties.Deref()
+		for h := uint32(0); h < physicalDevices[k].MemoryProperties.MemoryTypeCount; h++ {
+			physicalDevices[k].MemoryProperties.MemoryTypes[h].Deref()
+		}
+		for h := uint32(0); h < physicalDevices[k].MemoryProperties.MemoryHeapCount; h++ {
+			physicalDevices[k].MemoryProperties.MemoryHeaps[h].Deref()
+		}
+	}
+
+	// return the result.
+	return physicalDevices
+}
+
+func (phyDev PhysicalDevice) String() string {
+	devName := vk.ToString(phyDev.Properties.DeviceName[:])
+
+	devType := "other"
+	switch phyDev.Properties.DeviceType {
+	case vk.PhysicalDeviceTypeIntegratedGpu:
+		devType = "Integrated GPU"
+		break
+	case vk.PhysicalDeviceTypeDiscreteGpu:
+		devType = "Discrete GPU"
+		break
+	case vk.PhysicalDeviceTypeVirtualGpu:
+		devType = "Virtual GPU"
+		break
+	case vk.PhysicalDeviceTypeCpu:
+		devType = "CPU"
+		break
+	}
+
+	queueFamilyFlags := make([]string, len(phyDev.QueueFamilyProperties))
+	for h := 0; h < len(phyDev.QueueFamilyProperties); h++ {
+		queueFamilyFlags[h] = fmt.Sprintf("%d={flags: %05b}",
+			h,
+			phyDev.QueueFamilyProperties[h].QueueFlags)
+	}
+
+	return fmt.Sprintf("%s(%s) QueueFamilies:%v",
+		devName, devType,
+		queueFamilyFlags,
+	)
+}
+
+func (phyDev PhysicalDevice) QueueFamilies(surface vk.Surface) (graphics, presentation OptionUint32) {
+	// Iterate over Queue Families to find support.
+	for k, v := range phyDev.QueueFamilyProperties {
+		// cast as everything is expecting a uint32
+		index := uint32(k)
+
+		// Check if the queue supports graphics commands.
+		if v.QueueFlags&vk.QueueFlags(vk.QueueGraphicsBit) != 0 {
+			graphics.Set(index)
+		}
+
+		// Without a surface there is nothing to present to, so the
+		// graphics queue stands in for the presentation queue.
+		if surface == vk.Surface(vk.NullHandle) {
+			if graphics.IsSet() {
+				presentation.Set(graphics.Val())
+				break
+			}
+			continue
+		}
+
+		// check if this physical device can draw to our surface.
+		var presentSupport vk.Bool32
+		vk.GetPhysicalDeviceSurfaceSupport(
+			phyDev.Handle,
+			index,
+			surface,
+			&presentSupport,
+		)
+		if presentSupport.B() {
+			presentation.Set(index)
+		}
+
+		// If both families have values, we can stop iteration.
+		if graphics.IsSet() && presentation.IsSet() {
+			break
+		}
+	}
+	return graphics, presentation
+}
+
+func (phyDev PhysicalDevice) FindMemoryType(typeBits uint32, properties vk.MemoryPropertyFlags) (index OptionUint32) {
+	// Find the first allowed type that has all of the properties.
+	memProps := phyDev.MemoryProperties
+	for h := uint32(0); h < memProps.MemoryTypeCount; h++ {
+		if typeBits&(1<<h) == 0 {
+			continue
+		}
+		if memProps.MemoryTypes[h].PropertyFlags&properties == properties {
+			index.Set(h)
+			break
+		}
+	}
+	return index
+}
+
+// Returns the first format that has all of the features with the tiling.
+func (phyDev PhysicalDevice) FindSupportedFormat(candidates []vk.Format, tiling vk.ImageTiling, features vk.FormatFeatureFlags) (vk.Format, bool) {
+	for _, format := range candidates {
+		var formatProps vk.FormatProperties
+		vk.GetPhysicalDeviceFormatProperties(phyDev.Handle, format, &formatProps)
+		formatProps.Deref()
+
+		supported := formatProps.OptimalTilingFeatures
+		if tiling == vk.ImageTilingLinear {
+			supported = formatProps.LinearTilingFeatures
+		}
+		if supported&features == features {
+			return format, true
+		}
+	}
+	return vk.FormatUndefined, false
+}
+
+func (phyDev PhysicalDevice) SwapchainSupport(surface vk.Surface) (capabilities vk.SurfaceCapabilities, formats []vk.SurfaceFormat, presentModes []vk.PresentMode) {
+	// Get the intersection of capabilities.
+	vk.GetPhysicalDeviceSurfaceCapabilities(phyDev.Handle,
+		surface,
+		&capabilities)
+	capabilities.Deref()
+	capabilities.CurrentExtent.Deref()
+	capabilities.MinImageExtent.Deref()
+	capabilities.MaxImageExtent.Deref()
+
+	// 2-call enumerate the formats.
+	var count uint32
+	vk.GetPhysicalDeviceSurfaceFormats(phyDev.Handle,
+		surface,
+		&count,
+		nil)
+	formats = make([]vk.SurfaceFormat, count)
+	vk.GetPhysicalDeviceSurfaceFormats(phyDev.Handle,
+		surface,
+		&count,
+		formats)
+	for k, _ := range formats {
+		formats[k].Deref()
+	}
+
+	// 2-call enumerate the present modes.
+	vk.GetPhysicalDeviceSurfacePresentModes(phyDev.Handle,
+		surface,
+		&count,
+		nil)
+	presentModes = make([]vk.PresentMode, count)
+	vk.GetPhysicalDeviceSurfacePresentModes(phyDev.Handle,
+		surface,
+		&count,
+		presentModes)
+
+	return capabilities, formats, presentModes
+}
+
+// Selects the first device with graphics and presentation queues. When a
+// surface is given, the device must also support a swapchain for it.
+func DefaultSelectPhysicalDeviceIndex(physicalDevices []PhysicalDevice, surface vk.Surface) int {
+	for k, phyDev := range physicalDevices {
+		gIdx, pIdx := phyDev.QueueFamilies(surface)
+		if !gIdx.IsSet() || !pIdx.IsSet() {
+			continue
+		}
+		if surface != vk.Surface(vk.NullHandle) {
+			_, fmts, modes := phyDev.SwapchainSupport(surface)
+			if len(fmts) == 0 || len(modes) == 0 {
+				continue
+			}
+		}
+		fmt.Printf("Physical Device Selected: %d %s\n",
+			k,
+			phyDev)
+		return k
+	}
+	return -1
+}
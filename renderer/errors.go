@@ -0,0 +1,130 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Error classes, for use with errors.Is.
+var (
+	ErrOutOfDate    = errors.New("vulkan: swapchain out of date")
+	ErrSuboptimal   = errors.New("vulkan: swapchain suboptimal")
+	ErrSurfaceLost  = errors.New("vulkan: surface lost")
+	ErrDeviceLost   = errors.New("vulkan: device lost")
+	ErrOutOfMemory  = errors.New("vulkan: out of memory")
+	ErrInitFailed   = errors.New("vulkan: initialization failed")
+	ErrNotSupported = errors.New("vulkan: not supported")
+
+	// No Vulkan loader or physical device was found.
+	ErrNoVulkan = errors.New("vulkan: no loader or device")
+)
+
+// Result classes. Each entry lists the results that match the error class.
+var resultClasses = map[error][]vk.Result{
+	ErrOutOfDate:   {vk.ErrorOutOfDate},
+	ErrSuboptimal:  {vk.Suboptimal},
+	ErrSurfaceLost: {vk.ErrorSurfaceLost},
+	ErrDeviceLost:  {vk.ErrorDeviceLost},
+	ErrOutOfMemory: {
+		vk.ErrorOutOfHostMemory,
+		vk.ErrorOutOfDeviceMemory,
+		vk.Result(-1000069000), // VK_ERROR_OUT_OF_POOL_MEMORY
+	},
+	ErrInitFailed: {vk.ErrorInitializationFailed, vk.ErrorIncompatibleDriver},
+	ErrNotSupported: {
+		vk.ErrorLayerNotPresent,
+		vk.ErrorExtensionNotPresent,
+		vk.ErrorFeatureNotPresent,
+		vk.ErrorFormatNotSupported,
+	},
+}
+
+// Spec names for results. Results added after the core 1.0 and KHR surface
+// headers are keyed by value so they don't depend on the binding version.
+var resultNames = map[vk.Result]string{
+	vk.Success:                   "VK_SUCCESS",
+	vk.NotReady:                  "VK_NOT_READY",
+	vk.Timeout:                   "VK_TIMEOUT",
+	vk.EventSet:                  "VK_EVENT_SET",
+	vk.EventReset:                "VK_EVENT_RESET",
+	vk.Incomplete:                "VK_INCOMPLETE",
+	vk.ErrorOutOfHostMemory:      "VK_ERROR_OUT_OF_HOST_MEMORY",
+	vk.ErrorOutOfDeviceMemory:    "VK_ERROR_OUT_OF_DEVICE_MEMORY",
+	vk.ErrorInitializationFailed: "VK_ERROR_INITIALIZATION_FAILED",
+	vk.ErrorDeviceLost:           "VK_ERROR_DEVICE_LOST",
+	vk.ErrorMemoryMapFailed:      "VK_ERROR_MEMORY_MAP_FAILED",
+	vk.ErrorLayerNotPresent:      "VK_ERROR_LAYER_NOT_PRESENT",
+	vk.ErrorExtensionNotPresent:  "VK_ERROR_EXTENSION_NOT_PRESENT",
+	vk.ErrorFeatureNotPresent:    "VK_ERROR_FEATURE_NOT_PRESENT",
+	vk.ErrorIncompatibleDriver:   "VK_ERROR_INCOMPATIBLE_DRIVER",
+	vk.ErrorTooManyObjects:       "VK_ERROR_TOO_MANY_OBJECTS",
+	vk.ErrorFormatNotSupported:   "VK_ERROR_FORMAT_NOT_SUPPORTED",
+	vk.ErrorFragmentedPool:       "VK_ERROR_FRAGMENTED_POOL",
+	vk.Result(-13):               "VK_ERROR_UNKNOWN",
+	vk.Result(-1000069000):       "VK_ERROR_OUT_OF_POOL_MEMORY",
+	vk.Result(-1000072003):       "VK_ERROR_INVALID_EXTERNAL_HANDLE",
+	vk.Result(-1000161000):       "VK_ERROR_FRAGMENTATION",
+	vk.Result(-1000257000):       "VK_ERROR_INVALID_OPAQUE_CAPTURE_ADDRESS",
+	vk.ErrorSurfaceLost:          "VK_ERROR_SURFACE_LOST_KHR",
+	vk.ErrorNativeWindowInUse:    "VK_ERROR_NATIVE_WINDOW_IN_USE_KHR",
+	vk.Suboptimal:                "VK_SUBOPTIMAL_KHR",
+	vk.ErrorOutOfDate:            "VK_ERROR_OUT_OF_DATE_KHR",
+	vk.ErrorIncompatibleDisplay:  "VK_ERROR_INCOMPATIBLE_DISPLAY_KHR",
+	vk.ErrorValidationFailed:     "VK_ERROR_VALIDATION_FAILED_EXT",
+	vk.ErrorInvalidShaderNv:      "VK_ERROR_INVALID_SHADER_NV",
+	vk.Result(-1000158000):       "VK_ERROR_INVALID_DRM_FORMAT_MODIFIER_PLANE_LAYOUT_EXT",
+	vk.Result(-1000174001):       "VK_ERROR_NOT_PERMITTED_EXT",
+	vk.Result(-1000255000):       "VK_ERROR_FULL_SCREEN_EXCLUSIVE_MODE_LOST_EXT",
+}
+
+// Spec name of a result, e.g. VK_ERROR_OUT_OF_DATE_KHR.
+func ResultName(result vk.Result) string {
+	if name, ok := resultNames[result]; ok {
+		return name
+	}
+	return fmt.Sprintf("VK_RESULT_%d", int32(result))
+}
+
+// Vulkan Error
+type VulkanError struct {
+	Result vk.Result // The value returned by Vulkan.
+	Name   string    // The spec name of Result.
+	Func   string    // The Vulkan function that returned Result.
+	Info   string    // Summary of the create-info passed to Func, if any.
+}
+
+func NewVulkanError(call string, result vk.Result, info string) *VulkanError {
+	return &VulkanError{
+		Result: result,
+		Name:   ResultName(result),
+		Func:   call,
+		Info:   info,
+	}
+}
+
+func (err *VulkanError) Error() string {
+	msg := fmt.Sprintf("%s (%d)", err.Name, int32(err.Result))
+	if err.Func != "" {
+		msg = fmt.Sprintf("%s failed: %s", err.Func, msg)
+	}
+	if err.Info != "" {
+		msg = fmt.Sprintf("%s [%s]", msg, err.Info)
+	}
+	return msg
+}
+
+// Is reports if the result belongs to the target error class, or if target
+// is a VulkanError with the same result.
+func (err *VulkanError) Is(target error) bool {
+	if other, ok := target.(*VulkanError); ok {
+		return other.Result == err.Result
+	}
+	for _, result := range resultClasses[target] {
+		if result == err.Result {
+			return true
+		}
+	}
+	return false
+}
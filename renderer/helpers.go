@@ -1,7 +1,6 @@
-package main
+package renderer
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io/ioutil"
@@ -10,8 +9,19 @@ import (
 )
 
 // Error handling
+func CheckResult(call string, result vk.Result) error {
+	return CheckResultInfo(call, result, "")
+}
+
+func CheckResultInfo(call string, result vk.Result, info string) error {
+	if result == vk.Success {
+		return nil
+	}
+	return NewVulkanError(call, result, info)
+}
+
 func MustSucceed(result vk.Result) {
-	err := vk.Error(result)
+	err := CheckResult("", result)
 	if err != nil {
 		panic(err)
 	}
@@ -89,14 +99,29 @@ func DedupeSlice(a []string) []string {
 	return output
 }
 
-func MustSupport(available, required []string) {
+func CheckSupport(available, required []string) error {
 	missing := SetSubtraction(required, SliceToMap(available))
 	if len(missing) > 0 {
-		err := fmt.Errorf("Required values %v not found in %v.", missing, available)
+		return fmt.Errorf("Required values %v not found in %v.", missing, available)
+	}
+	return nil
+}
+
+func MustSupport(available, required []string) {
+	err := CheckSupport(available, required)
+	if err != nil {
 		panic(err)
 	}
 }
 
+// Defaults
+func OrDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
 // Clamp-able
 func ClampUint32(v, smallest, largest uint32) uint32 {
 	return MaxUint32(smallest, MinUint32(v, largest))
@@ -128,10 +153,13 @@ func MustReadFile(fn string) []byte {
 // 32-bit Words
 type WordsUint32 []uint32
 
+// NewWordsUint32 reads little-endian words, ignoring any trailing bytes. Use
+// ParseSPIRV for shader modules, it validates the input.
 func NewWordsUint32(b []byte) WordsUint32 {
-	r := bytes.NewReader(b)
 	words := make([]uint32, len(b)/4)
-	binary.Read(r, binary.LittleEndian, words)
+	for k := range words {
+		words[k] = binary.LittleEndian.Uint32(b[k*4:])
+	}
 	return WordsUint32(words)
 }
 
@@ -0,0 +1,44 @@
+package renderer
+
+import (
+	"time"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// ApplicationHooks lets an application plug its own content into the
+// renderer without replacing the frame loop.
+type ApplicationHooks interface {
+	// OnSetup is called once the device and first pipeline exist.
+	OnSetup(app *TriangleApplication) error
+
+	// OnUpdate is called once per frame before the frame is drawn, with the
+	// time elapsed since the previous frame.
+	OnUpdate(dt time.Duration) error
+
+	// OnRecord is called every frame while the command buffer for the
+	// acquired image is recording, inside the render pass with the first
+	// pipeline bound.
+	OnRecord(cmd vk.CommandBuffer, imageIndex uint32) error
+
+	// OnResize is called after the swapchain has been recreated.
+	OnResize(extent vk.Extent2D) error
+
+	// OnCleanup is called before the device is destroyed, if OnSetup
+	// succeeded.
+	OnCleanup()
+}
+
+// TriangleHooks draws the tutorial triangle. Embed it to override a subset
+// of the hooks.
+type TriangleHooks struct{}
+
+func (TriangleHooks) OnSetup(*TriangleApplication) error { return nil }
+func (TriangleHooks) OnUpdate(time.Duration) error       { return nil }
+func (TriangleHooks) OnResize(vk.Extent2D) error         { return nil }
+func (TriangleHooks) OnCleanup()                         {}
+
+func (TriangleHooks) OnRecord(cmd vk.CommandBuffer, imageIndex uint32) error {
+	vk.CmdDraw(cmd, 3, 1, 0, 0)
+	return nil
+}
@@ -0,0 +1,73 @@
+package renderer
+
+import (
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// LayerProperties
+func EnumerateInstanceLayerProperties() []vk.LayerProperties {
+	// Allocate the count.
+	var count uint32
+
+	// Call to get the count.
+	vk.EnumerateInstanceLayerProperties(&count, nil)
+
+	// Allocate to store the data.
+	list := make([]vk.LayerProperties, count)
+
+	// Call to get the data.
+	vk.EnumerateInstanceLayerProperties(&count, list)
+
+	// Dereference the data.
+	for k, _ := range list {
+		list[k].Deref()
+	}
+
+	// Return the result.
+	return list
+}
+
+// ExtensionProperties
+func EnumerateInstanceExtensionProperties(layerName string) []vk.ExtensionProperties {
+	// Allocate the count.
+	var count uint32
+
+	// Call to get the count.
+	vk.EnumerateInstanceExtensionProperties(layerName, &count, nil)
+
+	// Allocate to store the data.
+	list := make([]vk.ExtensionProperties, count)
+
+	// Call to get the data.
+	vk.EnumerateInstanceExtensionProperties(layerName, &count, list)
+
+	// Dereference the data.
+	for k, _ := range list {
+		list[k].Deref()
+	}
+
+	// Return the result.
+	return list
+}
+
+// Properties to Strings
+func LayerPropertiesNamesAndDescriptions(props []vk.LayerProperties) ([]string, []string) {
+	names, descs := make([]string, len(props)), make([]string, len(props))
+
+	for k, p := range props {
+		names[k] = vk.ToString(p.LayerName[:])
+		descs[k] = vk.ToString(p.Description[:])
+	}
+
+	return names, descs
+}
+
+func ExtensionPropertiesNames(props []vk.ExtensionProperties) []string {
+	names := make([]string, len(props))
+
+	for k, p := range props {
+		names[k] = vk.ToString(p.ExtensionName[:])
+	}
+
+	return names
+}
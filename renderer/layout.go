@@ -0,0 +1,258 @@
+package renderer
+
+import (
+	"fmt"
+	"sort"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Image dimensions that change the descriptor type.
+const (
+	dimBuffer      = 5
+	dimSubpassData = 6
+)
+
+// A descriptor set and binding number.
+type SetBinding struct {
+	Set     uint32
+	Binding uint32
+}
+
+// A reflected descriptor binding.
+type DescriptorBinding struct {
+	Set            uint32
+	Binding        uint32
+	Name           string
+	DescriptorType vk.DescriptorType
+	Count          uint32
+	StageFlags     vk.ShaderStageFlags
+	Type           *Type
+}
+
+func (binding DescriptorBinding) String() string {
+	return fmt.Sprintf("set=%d binding=%d %s type=%d count=%d stages=%#x",
+		binding.Set, binding.Binding,
+		binding.Name,
+		binding.DescriptorType,
+		binding.Count,
+		binding.StageFlags)
+}
+
+// Size in bytes of a type, using the Offset, ArrayStride and MatrixStride
+// decorations when they are present.
+func (t *Type) Size() uint32 {
+	switch t.Kind {
+	case TypeBool:
+		return 4
+	case TypeInt, TypeFloat:
+		return t.Width / 8
+	case TypeVector:
+		return t.Count * t.Elem.Size()
+	case TypeMatrix:
+		if t.Decorations.MatrixStride.IsSet() {
+			return t.Count * t.Decorations.MatrixStride.Val()
+		}
+		return t.Count * t.Elem.Size()
+	case TypeArray:
+		if t.Decorations.ArrayStride.IsSet() {
+			return t.Count * t.Decorations.ArrayStride.Val()
+		}
+		return t.Count * t.Elem.Size()
+	case TypeStruct:
+		size := uint32(0)
+		offset := uint32(0)
+		for k, m := range t.Members {
+			if t.MemberDecorations[k].Offset.IsSet() {
+				offset = t.MemberDecorations[k].Offset.Val()
+			}
+			size = MaxUint32(size, offset+m.Size())
+			offset += m.Size()
+		}
+		return size
+	}
+	return 0
+}
+
+// The descriptor bindings of the resource variables the entry point uses,
+// sorted by set and binding. The bindings are used by the entry point's
+// stage.
+func (refl *Reflection) DescriptorBindings(entryPoint EntryPoint) ([]DescriptorBinding, error) {
+	stage := entryPoint.Model.ShaderStage()
+	bindings := make([]DescriptorBinding, 0)
+	for _, v := range refl.Variables {
+		// Only resource variables have descriptors.
+		if v.StorageClass != StorageClassUniform &&
+			v.StorageClass != StorageClassUniformConstant &&
+			v.StorageClass != StorageClassStorageBuffer {
+			continue
+		}
+		if !entryPoint.Uses[v.ID] {
+			continue
+		}
+		if !v.Decorations.Binding.IsSet() {
+			return nil, fmt.Errorf("spirv: resource variable %d %s has no binding", v.ID, v.Name)
+		}
+
+		// Arrays of resources.
+		t, count := v.Type, uint32(1)
+		switch t.Kind {
+		case TypeArray:
+			t, count = t.Elem, t.Count
+		case TypeRuntimeArray:
+			return nil, fmt.Errorf("spirv: resource variable %d %s is a runtime array", v.ID, v.Name)
+		}
+
+		// Create the result object.
+		binding := DescriptorBinding{
+			Binding:    v.Decorations.Binding.Val(),
+			Name:       v.Name,
+			Count:      count,
+			StageFlags: vk.ShaderStageFlags(stage),
+			Type:       t,
+		}
+		if v.Decorations.DescriptorSet.IsSet() {
+			binding.Set = v.Decorations.DescriptorSet.Val()
+		}
+		if binding.Name == "" {
+			binding.Name = t.Name
+		}
+
+		// Work out the descriptor type.
+		switch {
+		case v.StorageClass == StorageClassStorageBuffer,
+			v.StorageClass == StorageClassUniform && t.Decorations.BufferBlock:
+			binding.DescriptorType = vk.DescriptorTypeStorageBuffer
+		case v.StorageClass == StorageClassUniform:
+			binding.DescriptorType = vk.DescriptorTypeUniformBuffer
+		case t.Kind == TypeSampler:
+			binding.DescriptorType = vk.DescriptorTypeSampler
+		case t.Kind == TypeSampledImage:
+			binding.DescriptorType = vk.DescriptorTypeCombinedImageSampler
+		case t.Kind == TypeImage && t.Dim == dimSubpassData:
+			binding.DescriptorType = vk.DescriptorTypeInputAttachment
+		case t.Kind == TypeImage && t.Dim == dimBuffer && t.Sampled == 1:
+			binding.DescriptorType = vk.DescriptorTypeUniformTexelBuffer
+		case t.Kind == TypeImage && t.Dim == dimBuffer:
+			binding.DescriptorType = vk.DescriptorTypeStorageTexelBuffer
+		case t.Kind == TypeImage && t.Sampled == 1:
+			binding.DescriptorType = vk.DescriptorTypeSampledImage
+		case t.Kind == TypeImage:
+			binding.DescriptorType = vk.DescriptorTypeStorageImage
+		default:
+			return nil, fmt.Errorf("spirv: resource variable %d %s has unsupported type %s", v.ID, v.Name, t)
+		}
+		bindings = append(bindings, binding)
+	}
+
+	// Sort by set and binding.
+	sort.Slice(bindings, func(a, b int) bool {
+		if bindings[a].Set != bindings[b].Set {
+			return bindings[a].Set < bindings[b].Set
+		}
+		return bindings[a].Binding < bindings[b].Binding
+	})
+	return bindings, nil
+}
+
+// The push constant range of the push constant block the entry point uses,
+// if it uses one. The range only covers the members of the block.
+func (refl *Reflection) PushConstantRanges(entryPoint EntryPoint) []vk.PushConstantRange {
+	stage := entryPoint.Model.ShaderStage()
+	ranges := make([]vk.PushConstantRange, 0)
+	for _, v := range refl.Variables {
+		if v.StorageClass != StorageClassPushConstant || v.Type.Kind != TypeStruct || len(v.Type.Members) == 0 {
+			continue
+		}
+		if !entryPoint.Uses[v.ID] {
+			continue
+		}
+
+		// The block starts at its first member.
+		t := v.Type
+		offset := uint32(0)
+		if t.MemberDecorations[0].Offset.IsSet() {
+			offset = t.MemberDecorations[0].Offset.Val()
+		}
+		ranges = append(ranges, vk.PushConstantRange{
+			StageFlags: vk.ShaderStageFlags(stage),
+			Offset:     offset,
+			Size:       t.Size() - offset,
+		})
+	}
+	return ranges
+}
+
+// Merge the descriptor bindings of several stages. Bindings at the same set
+// and binding must agree on the descriptor type and count; their stage
+// flags are combined.
+func MergeDescriptorBindings(stages ...[]DescriptorBinding) ([]DescriptorBinding, error) {
+	type key struct{ set, binding uint32 }
+	merged := make([]DescriptorBinding, 0)
+	index := make(map[key]int)
+	for _, bindings := range stages {
+		for _, binding := range bindings {
+			k := key{binding.Set, binding.Binding}
+			h, ok := index[k]
+			if !ok {
+				index[k] = len(merged)
+				merged = append(merged, binding)
+				continue
+			}
+			if merged[h].DescriptorType != binding.DescriptorType || merged[h].Count != binding.Count {
+				return nil, fmt.Errorf("descriptor set=%d binding=%d is declared differently by two stages: %s and %s",
+					binding.Set, binding.Binding, merged[h], binding)
+			}
+			merged[h].StageFlags |= binding.StageFlags
+		}
+	}
+
+	// Sort by set and binding.
+	sort.Slice(merged, func(a, b int) bool {
+		if merged[a].Set != merged[b].Set {
+			return merged[a].Set < merged[b].Set
+		}
+		return merged[a].Binding < merged[b].Binding
+	})
+	return merged, nil
+}
+
+// Merge the push constant ranges of several stages. Overlapping ranges are
+// combined into one range used by all their stages.
+func MergePushConstantRanges(stages ...[]vk.PushConstantRange) []vk.PushConstantRange {
+	all := make([]vk.PushConstantRange, 0)
+	for _, ranges := range stages {
+		all = append(all, ranges...)
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a].Offset < all[b].Offset })
+
+	merged := make([]vk.PushConstantRange, 0, len(all))
+	for _, r := range all {
+		if n := len(merged); n > 0 && r.Offset < merged[n-1].Offset+merged[n-1].Size {
+			last := &merged[n-1]
+			last.Size = MaxUint32(last.Offset+last.Size, r.Offset+r.Size) - last.Offset
+			last.StageFlags |= r.StageFlags
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Group bindings into descriptor set layout bindings, indexed by set. Sets
+// without bindings are left empty so the indices stay contiguous.
+func DescriptorSetLayoutBindings(bindings []DescriptorBinding) [][]vk.DescriptorSetLayoutBinding {
+	sets := make([][]vk.DescriptorSetLayoutBinding, 0)
+	for _, binding := range bindings {
+		for uint32(len(sets)) <= binding.Set {
+			sets = append(sets, []vk.DescriptorSetLayoutBinding{})
+		}
+		sets[binding.Set] = append(sets[binding.Set], vk.DescriptorSetLayoutBinding{
+			Binding:         binding.Binding,
+			DescriptorType:  binding.DescriptorType,
+			DescriptorCount: binding.Count,
+			StageFlags:      binding.StageFlags,
+		})
+	}
+	return sets
+}
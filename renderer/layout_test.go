@@ -0,0 +1,83 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestDescriptorBindingsUsedByEntryPoint(t *testing.T) {
+	const (
+		opFunctionCall = 57
+		opLoad         = 61
+	)
+
+	// Two entry points sharing a module. Only the operands of the function
+	// bodies matter, so the loads don't bother with matching result types.
+	m := newTestModule()
+	block := func(name string, storage StorageClass, decs ...[]uint32) uint32 {
+		return m.variable(testVar{name: name, decs: decs, members: []testVar{
+			{name: "value", typ: "vec4", decs: [][]uint32{{decorationOffset, 0}}},
+		}}, storage)
+	}
+	camera := block("camera", StorageClassUniform, []uint32{decorationDescriptorSet, 0}, []uint32{decorationBinding, 0})
+	light := block("light", StorageClassUniform, []uint32{decorationDescriptorSet, 0}, []uint32{decorationBinding, 1})
+	shadow := block("shadow", StorageClassUniform, []uint32{decorationDescriptorSet, 1}, []uint32{decorationBinding, 0})
+	push := block("push", StorageClassPushConstant)
+	vec4, void := m.typeID("vec4"), m.typeID("void")
+
+	helper := m.function(
+		[]uint32{opLoad, vec4, m.id(), light},
+	)
+	mainFunction := m.function(
+		[]uint32{opLoad, vec4, m.id(), camera},
+		[]uint32{opFunctionCall, void, m.id(), helper},
+		[]uint32{opLoad, vec4, m.id(), push},
+	)
+	shadowFunction := m.function(
+		[]uint32{opLoad, vec4, m.id(), shadow},
+	)
+	m.namedEntryPoint(ExecutionModelVertex, "main", mainFunction)
+	m.namedEntryPoint(ExecutionModelVertex, "shadow", shadowFunction)
+	refl := m.reflect(t)
+
+	tests := []struct {
+		entryPoint string
+		bindings   []SetBinding
+		names      []string
+		pushRanges int
+	}{
+		{"main", []SetBinding{{0, 0}, {0, 1}}, []string{"camera", "light"}, 1},
+		{"shadow", []SetBinding{{1, 0}}, []string{"shadow"}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.entryPoint, func(t *testing.T) {
+			ep, err := refl.FindEntryPoint(test.entryPoint, ExecutionModelVertex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bindings, err := refl.DescriptorBindings(ep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotBindings := make([]SetBinding, len(bindings))
+			gotNames := make([]string, len(bindings))
+			for k, b := range bindings {
+				gotBindings[k] = SetBinding{b.Set, b.Binding}
+				gotNames[k] = b.Name
+				if b.DescriptorType != vk.DescriptorTypeUniformBuffer || b.StageFlags != vk.ShaderStageFlags(vk.ShaderStageVertexBit) {
+					t.Errorf("binding %s", b)
+				}
+			}
+			if !reflect.DeepEqual(gotBindings, test.bindings) || !reflect.DeepEqual(gotNames, test.names) {
+				t.Errorf("bindings %v %v, expected %v %v", gotBindings, gotNames, test.bindings, test.names)
+			}
+
+			ranges := refl.PushConstantRanges(ep)
+			if len(ranges) != test.pushRanges {
+				t.Errorf("%d push constant ranges, expected %d", len(ranges), test.pushRanges)
+			}
+		})
+	}
+}
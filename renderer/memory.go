@@ -0,0 +1,181 @@
+package renderer
+
+import (
+	"fmt"
+	"math/bits"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// MemoryBlock keeps track of the ranges of a block of device memory that
+// are in use. It only does the bookkeeping, so it works without a device.
+//
+// Linear resources (buffers and linear images) and optimal images must not
+// share a page of Granularity bytes, so allocations of one kind are kept off
+// the pages of their neighbours of the other kind.
+type MemoryBlock struct {
+	Size        vk.DeviceSize
+	Granularity vk.DeviceSize // BufferImageGranularity.
+
+	// The segments cover the whole block in offset order. Free segments
+	// are merged with their free neighbours, so a free segment is always
+	// between allocations.
+	segments []memorySegment
+	used     vk.DeviceSize
+}
+
+type memorySegment struct {
+	Offset vk.DeviceSize
+	Size   vk.DeviceSize
+	Free   bool
+	Linear bool
+}
+
+// Create the bookkeeping for an empty block.
+func NewMemoryBlock(size, granularity vk.DeviceSize) *MemoryBlock {
+	if granularity == 0 {
+		granularity = 1
+	}
+	return &MemoryBlock{
+		Size:        size,
+		Granularity: granularity,
+		segments: []memorySegment{
+			{Offset: 0, Size: size, Free: true},
+		},
+	}
+}
+
+// Round v up to a multiple of alignment.
+func alignUp(v, alignment vk.DeviceSize) vk.DeviceSize {
+	if alignment <= 1 {
+		return v
+	}
+	return (v + alignment - 1) / alignment * alignment
+}
+
+// Reports if the last byte before end and the byte at start are on the same
+// page.
+func samePage(end, start, granularity vk.DeviceSize) bool {
+	return (end-1)/granularity == start/granularity
+}
+
+// Allocate finds room for size bytes at a multiple of alignment, first fit.
+// It returns the offset, or false if the block is too full.
+func (block *MemoryBlock) Allocate(size, alignment vk.DeviceSize, linear bool) (vk.DeviceSize, bool) {
+	if size == 0 {
+		return 0, false
+	}
+	for k, seg := range block.segments {
+		if !seg.Free {
+			continue
+		}
+
+		// Align the start, moving off the page of a different kind of
+		// resource before it.
+		offset := alignUp(seg.Offset, alignment)
+		if k > 0 {
+			prev := block.segments[k-1]
+			if prev.Linear != linear && samePage(prev.Offset+prev.Size, offset, block.Granularity) {
+				offset = alignUp(offset, block.Granularity)
+			}
+		}
+
+		// The end must fit, and stay off the page of a different kind of
+		// resource after it.
+		end := offset + size
+		if end > seg.Offset+seg.Size {
+			continue
+		}
+		if k+1 < len(block.segments) {
+			next := block.segments[k+1]
+			if next.Linear != linear && samePage(end, next.Offset, block.Granularity) {
+				continue
+			}
+		}
+
+		// Split the free segment around the allocation.
+		split := make([]memorySegment, 0, 3)
+		if offset > seg.Offset {
+			split = append(split, memorySegment{Offset: seg.Offset, Size: offset - seg.Offset, Free: true})
+		}
+		split = append(split, memorySegment{Offset: offset, Size: size, Linear: linear})
+		if segEnd := seg.Offset + seg.Size; end < segEnd {
+			split = append(split, memorySegment{Offset: end, Size: segEnd - end, Free: true})
+		}
+		segments := make([]memorySegment, 0, len(block.segments)+len(split)-1)
+		segments = append(segments, block.segments[:k]...)
+		segments = append(segments, split...)
+		block.segments = append(segments, block.segments[k+1:]...)
+
+		block.used += size
+		return offset, true
+	}
+	return 0, false
+}
+
+// Free the allocation at offset, merging it with free neighbours.
+func (block *MemoryBlock) Free(offset vk.DeviceSize) error {
+	for k, seg := range block.segments {
+		if seg.Offset != offset || seg.Free {
+			continue
+		}
+		block.used -= seg.Size
+
+		// Merge with the free segments on either side.
+		first, last := k, k
+		if first > 0 && block.segments[first-1].Free {
+			first--
+		}
+		if last+1 < len(block.segments) && block.segments[last+1].Free {
+			last++
+		}
+		merged := memorySegment{
+			Offset: block.segments[first].Offset,
+			Size:   block.segments[last].Offset + block.segments[last].Size - block.segments[first].Offset,
+			Free:   true,
+		}
+		block.segments = append(append(block.segments[:first:first], merged), block.segments[last+1:]...)
+		return nil
+	}
+	return fmt.Errorf("memory block: no allocation at offset %d", offset)
+}
+
+// Bytes in use, not counting alignment padding.
+func (block *MemoryBlock) Used() vk.DeviceSize {
+	return block.used
+}
+
+// Reports if nothing is allocated from the block.
+func (block *MemoryBlock) Empty() bool {
+	return len(block.segments) == 1 && block.segments[0].Free
+}
+
+// The free ranges, in offset order, as offset and size pairs.
+func (block *MemoryBlock) FreeRanges() [][2]vk.DeviceSize {
+	ranges := make([][2]vk.DeviceSize, 0, len(block.segments))
+	for _, seg := range block.segments {
+		if seg.Free {
+			ranges = append(ranges, [2]vk.DeviceSize{seg.Offset, seg.Size})
+		}
+	}
+	return ranges
+}
+
+// ChooseMemoryType returns the memory type allowed by typeBits that has all
+// of the required properties and the most of the preferred ones. Ties go to
+// the lowest index, which drivers order by preference.
+func ChooseMemoryType(props vk.PhysicalDeviceMemoryProperties, typeBits uint32, required, preferred vk.MemoryPropertyFlags) (index OptionUint32) {
+	best := -1
+	for h := uint32(0); h < props.MemoryTypeCount; h++ {
+		flags := props.MemoryTypes[h].PropertyFlags
+		if typeBits&(1<<h) == 0 || flags&required != required {
+			continue
+		}
+		score := bits.OnesCount32(uint32(flags & preferred))
+		if score > best {
+			best = score
+			index.Set(h)
+		}
+	}
+	return index
+}
@@ -0,0 +1,190 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// One allocation or free, and what it should return.
+type memoryStep struct {
+	free   bool
+	size   vk.DeviceSize
+	align  vk.DeviceSize
+	linear bool
+	offset vk.DeviceSize // Allocated, or freed.
+	ok     bool
+}
+
+func alloc(size, align vk.DeviceSize, linear bool, offset vk.DeviceSize) memoryStep {
+	return memoryStep{size: size, align: align, linear: linear, offset: offset, ok: true}
+}
+
+func allocFails(size, align vk.DeviceSize, linear bool) memoryStep {
+	return memoryStep{size: size, align: align, linear: linear}
+}
+
+func free(offset vk.DeviceSize) memoryStep {
+	return memoryStep{free: true, offset: offset, ok: true}
+}
+
+func freeFails(offset vk.DeviceSize) memoryStep {
+	return memoryStep{free: true, offset: offset}
+}
+
+func TestMemoryBlock(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        vk.DeviceSize
+		granularity vk.DeviceSize
+		steps       []memoryStep
+		used        vk.DeviceSize
+		freeRanges  [][2]vk.DeviceSize
+	}{
+		{
+			name: "first fit", size: 1024, granularity: 1,
+			steps: []memoryStep{
+				alloc(256, 1, true, 0),
+				alloc(256, 1, true, 256),
+				free(0),
+				alloc(128, 1, true, 0),
+				alloc(256, 1, true, 512),
+			},
+			used:       640,
+			freeRanges: [][2]vk.DeviceSize{{128, 128}, {768, 256}},
+		},
+		{
+			name: "alignment", size: 1024, granularity: 1,
+			steps: []memoryStep{
+				alloc(10, 1, true, 0),
+				alloc(10, 64, true, 64),
+				alloc(1, 256, true, 256),
+				alloc(100, 16, true, 80),
+			},
+			used:       121,
+			freeRanges: [][2]vk.DeviceSize{{10, 54}, {74, 6}, {180, 76}, {257, 767}},
+		},
+		{
+			name: "granularity before", size: 4096, granularity: 1024,
+			steps: []memoryStep{
+				alloc(100, 16, true, 0),
+				alloc(100, 16, false, 1024),
+				alloc(100, 16, true, 112),
+			},
+			used:       300,
+			freeRanges: [][2]vk.DeviceSize{{100, 12}, {212, 812}, {1124, 2972}},
+		},
+		{
+			name: "granularity after", size: 4096, granularity: 1024,
+			steps: []memoryStep{
+				alloc(100, 1, true, 0),
+				alloc(100, 1, false, 1024),
+				free(0),
+				alloc(50, 1, false, 0),
+				alloc(50, 1, true, 2048),
+			},
+			used:       200,
+			freeRanges: [][2]vk.DeviceSize{{50, 974}, {1124, 924}, {2098, 1998}},
+		},
+		{
+			name: "coalescing", size: 3072, granularity: 1,
+			steps: []memoryStep{
+				alloc(1024, 1, true, 0),
+				alloc(1024, 1, true, 1024),
+				alloc(1024, 1, true, 2048),
+				free(1024),
+				free(0),
+				free(2048),
+				alloc(3072, 1, true, 0),
+				free(0),
+			},
+			used:       0,
+			freeRanges: [][2]vk.DeviceSize{{0, 3072}},
+		},
+		{
+			name: "exhaustion", size: 1024, granularity: 1,
+			steps: []memoryStep{
+				allocFails(2048, 1, true),
+				alloc(1000, 1, true, 0),
+				allocFails(32, 1, true),
+				alloc(24, 1, true, 1000),
+				allocFails(1, 1, true),
+				allocFails(0, 1, true),
+				freeFails(512),
+				free(0),
+				freeFails(0),
+			},
+			used:       24,
+			freeRanges: [][2]vk.DeviceSize{{0, 1000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := NewMemoryBlock(tt.size, tt.granularity)
+			for k, step := range tt.steps {
+				if step.free {
+					err := block.Free(step.offset)
+					if (err == nil) != step.ok {
+						t.Fatalf("step %d: Free(%d) = %v, want ok=%t", k, step.offset, err, step.ok)
+					}
+					continue
+				}
+				offset, ok := block.Allocate(step.size, step.align, step.linear)
+				if ok != step.ok || (ok && offset != step.offset) {
+					t.Fatalf("step %d: Allocate(%d, %d, %t) = %d, %t, want %d, %t",
+						k, step.size, step.align, step.linear, offset, ok, step.offset, step.ok)
+				}
+			}
+			if block.Used() != tt.used {
+				t.Errorf("Used() = %d, want %d", block.Used(), tt.used)
+			}
+			if got := block.FreeRanges(); !reflect.DeepEqual(got, tt.freeRanges) {
+				t.Errorf("FreeRanges() = %v, want %v", got, tt.freeRanges)
+			}
+			if empty := tt.used == 0; block.Empty() != empty {
+				t.Errorf("Empty() = %t, want %t", block.Empty(), empty)
+			}
+		})
+	}
+}
+
+func TestChooseMemoryType(t *testing.T) {
+	const (
+		deviceLocal  = vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit)
+		hostVisible  = vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit)
+		hostCoherent = vk.MemoryPropertyFlags(vk.MemoryPropertyHostCoherentBit)
+		hostCached   = vk.MemoryPropertyFlags(vk.MemoryPropertyHostCachedBit)
+	)
+	props := vk.PhysicalDeviceMemoryProperties{MemoryTypeCount: 4}
+	props.MemoryTypes[0].PropertyFlags = deviceLocal
+	props.MemoryTypes[1].PropertyFlags = hostVisible | hostCoherent
+	props.MemoryTypes[2].PropertyFlags = hostVisible | hostCoherent | hostCached
+	props.MemoryTypes[3].PropertyFlags = deviceLocal | hostVisible | hostCoherent
+
+	tests := []struct {
+		name                string
+		typeBits            uint32
+		required, preferred vk.MemoryPropertyFlags
+		want                int // -1 for none.
+	}{
+		{"device local", 0xf, deviceLocal, 0, 0},
+		{"host visible", 0xf, hostVisible, 0, 1},
+		{"prefer cached", 0xf, hostVisible, hostCached, 2},
+		{"prefer device local", 0xf, hostVisible | hostCoherent, deviceLocal, 3},
+		{"type bits", 0x6, deviceLocal, 0, -1},
+		{"type bits with preference", 0x3, hostVisible, deviceLocal, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := ChooseMemoryType(props, tt.typeBits, tt.required, tt.preferred)
+			got := -1
+			if index.IsSet() {
+				got = int(index.Val())
+			}
+			if got != tt.want {
+				t.Errorf("ChooseMemoryType() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
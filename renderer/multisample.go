@@ -0,0 +1,27 @@
+package renderer
+
+import (
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// ClampSampleCount returns the largest of the supported sample counts that
+// is no more than requested. One sample is always supported.
+func ClampSampleCount(requested vk.SampleCountFlagBits, supported vk.SampleCountFlags) vk.SampleCountFlagBits {
+	for count := vk.SampleCount64Bit; count > vk.SampleCount1Bit; count >>= 1 {
+		if count <= requested && supported&vk.SampleCountFlags(count) != 0 {
+			return count
+		}
+	}
+	return vk.SampleCount1Bit
+}
+
+// Sample counts the device supports for framebuffers with a color
+// attachment, and a depth attachment if depth is set.
+func (phyDev PhysicalDevice) FramebufferSampleCounts(depth bool) vk.SampleCountFlags {
+	limits := phyDev.Properties.Limits
+	counts := limits.FramebufferColorSampleCounts
+	if depth {
+		counts &= limits.FramebufferDepthSampleCounts
+	}
+	return counts
+}
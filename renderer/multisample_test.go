@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestClampSampleCount(t *testing.T) {
+	upTo8 := vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount2Bit | vk.SampleCount4Bit | vk.SampleCount8Bit)
+	tests := []struct {
+		name      string
+		requested vk.SampleCountFlagBits
+		supported vk.SampleCountFlags
+		want      vk.SampleCountFlagBits
+	}{
+		{"supported", vk.SampleCount4Bit, upTo8, vk.SampleCount4Bit},
+		{"above the maximum", vk.SampleCount64Bit, upTo8, vk.SampleCount8Bit},
+		{"not a power of two", 6, upTo8, vk.SampleCount4Bit},
+		{"gap in the mask", vk.SampleCount8Bit, vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount2Bit | vk.SampleCount16Bit), vk.SampleCount2Bit},
+		{"zero mask", vk.SampleCount8Bit, 0, vk.SampleCount1Bit},
+		{"zero requested", 0, upTo8, vk.SampleCount1Bit},
+		{"one sample", vk.SampleCount1Bit, upTo8, vk.SampleCount1Bit},
+	}
+	for _, test := range tests {
+		if got := ClampSampleCount(test.requested, test.supported); got != test.want {
+			t.Errorf("%s: ClampSampleCount(%d, %#x) = %d, expected %d", test.name, test.requested, test.supported, got, test.want)
+		}
+	}
+}
+
+func TestFramebufferSampleCounts(t *testing.T) {
+	var phyDev PhysicalDevice
+	phyDev.Properties.Limits.FramebufferColorSampleCounts = vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount4Bit | vk.SampleCount8Bit)
+	phyDev.Properties.Limits.FramebufferDepthSampleCounts = vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount2Bit | vk.SampleCount4Bit)
+
+	if got, want := phyDev.FramebufferSampleCounts(false), vk.SampleCountFlags(vk.SampleCount1Bit|vk.SampleCount4Bit|vk.SampleCount8Bit); got != want {
+		t.Errorf("color only: %#x, expected %#x", got, want)
+	}
+	if got, want := phyDev.FramebufferSampleCounts(true), vk.SampleCountFlags(vk.SampleCount1Bit|vk.SampleCount4Bit); got != want {
+		t.Errorf("color and depth: %#x, expected %#x", got, want)
+	}
+	if got := ClampSampleCount(vk.SampleCount8Bit, phyDev.FramebufferSampleCounts(true)); got != vk.SampleCount4Bit {
+		t.Errorf("8 samples with depth clamped to %d, expected 4", got)
+	}
+}
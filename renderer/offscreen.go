@@ -0,0 +1,257 @@
+package renderer
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"time"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Headless frames are stepped by a fixed amount so the output is repeatable.
+const HeadlessFrameTime = time.Second / 60
+
+// Format of the offscreen color attachment. It matches the 8-bit sRGB
+// swapchain format the windowed mode prefers, in byte order that maps
+// directly onto image.RGBA.
+var HeadlessFormat = vk.SurfaceFormat{
+	Format:     vk.FormatR8g8b8a8Srgb,
+	ColorSpace: vk.ColorSpaceSrgbNonlinear,
+}
+
+func (app *TriangleApplication) headlessExtent() vk.Extent2D {
+	if app.HeadlessExtent.Width == 0 || app.HeadlessExtent.Height == 0 {
+		return vk.Extent2D{Width: WindowWidth, Height: WindowHeight}
+	}
+	return app.HeadlessExtent
+}
+
+// The formats ReadPixels understands that the device can render into,
+// HeadlessFormat first. They play the part of the surface formats when
+// choosing the offscreen image format.
+func offscreenSurfaceFormats(phyDev PhysicalDevice) []vk.SurfaceFormat {
+	candidates := []vk.Format{
+		HeadlessFormat.Format,
+		vk.FormatR8g8b8a8Srgb,
+		vk.FormatB8g8r8a8Srgb,
+		vk.FormatR8g8b8a8Unorm,
+		vk.FormatB8g8r8a8Unorm,
+	}
+	formats := make([]vk.SurfaceFormat, 0, len(candidates))
+	seen := make(map[vk.Format]bool)
+	for _, format := range candidates {
+		if seen[format] || !colorAttachmentSupported(phyDev, format) {
+			continue
+		}
+		seen[format] = true
+		formats = append(formats, vk.SurfaceFormat{
+			Format:     format,
+			ColorSpace: vk.ColorSpaceSrgbNonlinear,
+		})
+	}
+	return formats
+}
+
+func colorAttachmentSupported(phyDev PhysicalDevice, format vk.Format) bool {
+	_, ok := phyDev.FindSupportedFormat([]vk.Format{format},
+		vk.ImageTilingOptimal,
+		vk.FormatFeatureFlags(vk.FormatFeatureColorAttachmentBit))
+	return ok
+}
+
+// Create the image the headless mode renders into, in place of the
+// swapchain images.
+func newOffscreenImage(allocator *Allocator, phyDev PhysicalDevice, format vk.SurfaceFormat, extent vk.Extent2D) (vk.Image, MemoryAllocation, vk.SurfaceFormat, vk.Extent2D, error) {
+	// The format must be usable as a color attachment.
+	if !colorAttachmentSupported(phyDev, format.Format) {
+		return vk.Image(vk.NullHandle), MemoryAllocation{}, format, extent,
+			fmt.Errorf("format %d cannot be used as a color attachment", format.Format)
+	}
+
+	// Create the info object.
+	imageInfo := vk.ImageCreateInfo{
+		SType:     vk.StructureTypeImageCreateInfo,
+		ImageType: vk.ImageType2d,
+		Format:    format.Format,
+		Extent: vk.Extent3D{
+			Width:  extent.Width,
+			Height: extent.Height,
+			Depth:  1,
+		},
+		MipLevels:     1,
+		ArrayLayers:   1,
+		Samples:       vk.SampleCount1Bit,
+		Tiling:        vk.ImageTilingOptimal,
+		Usage:         vk.ImageUsageFlags(vk.ImageUsageColorAttachmentBit | vk.ImageUsageTransferSrcBit),
+		SharingMode:   vk.SharingModeExclusive,
+		InitialLayout: vk.ImageLayoutUndefined,
+	}
+
+	// Create the image.
+	img, alloc, err := allocator.AllocateImage(imageInfo,
+		vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit),
+		0)
+	return img, alloc, format, extent, err
+}
+
+// ReadPixels copies the offscreen color attachment into host memory. It is
+// only available in headless mode.
+func (app *TriangleApplication) ReadPixels() (*image.RGBA, error) {
+	if app.swapchain == nil || app.swapchain.OffscreenImage() == vk.Image(vk.NullHandle) {
+		return nil, fmt.Errorf("no offscreen image to read, is the application headless?")
+	}
+	extent := app.swapchain.Extent
+	size := vk.DeviceSize(extent.Width * extent.Height * 4)
+
+	// Only 4 byte RGBA and BGRA formats are supported.
+	swizzle := false
+	switch app.swapchain.ImageFormat {
+	case vk.FormatR8g8b8a8Srgb, vk.FormatR8g8b8a8Unorm:
+	case vk.FormatB8g8r8a8Srgb, vk.FormatB8g8r8a8Unorm:
+		swizzle = true
+	default:
+		return nil, fmt.Errorf("cannot read back format %d", app.swapchain.ImageFormat)
+	}
+
+	// Wait for all the frames to finish.
+	err := CheckResult("vkDeviceWaitIdle", vk.DeviceWaitIdle(app.device))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a buffer the host can read.
+	buffer, err := NewBuffer(app.allocator,
+		size,
+		vk.BufferUsageFlags(vk.BufferUsageTransferDstBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit|vk.MemoryPropertyHostCoherentBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyHostCachedBit))
+	if err != nil {
+		return nil, err
+	}
+	defer buffer.Cleanup()
+
+	// Copy the image into the buffer.
+	err = submitOneTime(app.device, app.graphicsCommandPool, app.graphicsQueue, func(cmd vk.CommandBuffer) {
+		// Make the render pass writes visible to the copy.
+		vk.CmdPipelineBarrier(cmd,
+			vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+			vk.PipelineStageFlags(vk.PipelineStageTransferBit),
+			0,
+			0, nil,
+			0, nil,
+			1, []vk.ImageMemoryBarrier{
+				vk.ImageMemoryBarrier{
+					SType:               vk.StructureTypeImageMemoryBarrier,
+					SrcAccessMask:       vk.AccessFlags(vk.AccessColorAttachmentWriteBit),
+					DstAccessMask:       vk.AccessFlags(vk.AccessTransferReadBit),
+					OldLayout:           vk.ImageLayoutTransferSrcOptimal,
+					NewLayout:           vk.ImageLayoutTransferSrcOptimal,
+					SrcQueueFamilyIndex: vk.QueueFamilyIgnored,
+					DstQueueFamilyIndex: vk.QueueFamilyIgnored,
+					Image:               app.swapchain.OffscreenImage(),
+					SubresourceRange: vk.ImageSubresourceRange{
+						AspectMask: vk.ImageAspectFlags(vk.ImageAspectColorBit),
+						LevelCount: 1,
+						LayerCount: 1,
+					},
+				},
+			})
+
+		// Copy the whole image.
+		vk.CmdCopyImageToBuffer(cmd,
+			app.swapchain.OffscreenImage(),
+			vk.ImageLayoutTransferSrcOptimal,
+			buffer.Handle,
+			1, []vk.BufferImageCopy{
+				vk.BufferImageCopy{
+					ImageSubresource: vk.ImageSubresourceLayers{
+						AspectMask: vk.ImageAspectFlags(vk.ImageAspectColorBit),
+						LayerCount: 1,
+					},
+					ImageExtent: vk.Extent3D{
+						Width:  extent.Width,
+						Height: extent.Height,
+						Depth:  1,
+					},
+				},
+			})
+
+		// Make the copy visible to the host.
+		vk.CmdPipelineBarrier(cmd,
+			vk.PipelineStageFlags(vk.PipelineStageTransferBit),
+			vk.PipelineStageFlags(vk.PipelineStageHostBit),
+			0,
+			1, []vk.MemoryBarrier{
+				vk.MemoryBarrier{
+					SType:         vk.StructureTypeMemoryBarrier,
+					SrcAccessMask: vk.AccessFlags(vk.AccessTransferWriteBit),
+					DstAccessMask: vk.AccessFlags(vk.AccessHostReadBit),
+				},
+			},
+			0, nil,
+			0, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Map the buffer.
+	data, err := app.allocator.Map(buffer.Allocation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the pixels out.
+	img := image.NewRGBA(image.Rect(0, 0, int(extent.Width), int(extent.Height)))
+	copy(img.Pix, unsafe.Slice((*byte)(data), int(size)))
+	if swizzle {
+		for h := 0; h < len(img.Pix); h += 4 {
+			img.Pix[h], img.Pix[h+2] = img.Pix[h+2], img.Pix[h]
+		}
+	}
+	return img, nil
+}
+
+// RenderHeadless sets up the application without a window, draws frames
+// frames and returns the last one. At least one frame has to be drawn, since
+// there is nothing to read back otherwise. The application is cleaned up
+// before returning.
+func (app *TriangleApplication) RenderHeadless(frames int) (*image.RGBA, error) {
+	if frames < 1 {
+		return nil, fmt.Errorf("can't render %d frames headless, at least 1 is needed", frames)
+	}
+	app.Headless = true
+	defer app.cleanup()
+	if err := app.setup(); err != nil {
+		return nil, err
+	}
+
+	// Draw the frames.
+	for h := 0; h < frames; h++ {
+		if err := app.hooks().OnUpdate(HeadlessFrameTime); err != nil {
+			return nil, fmt.Errorf("OnUpdate hook failed: %w", err)
+		}
+		if err := app.drawFrame(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the last frame back.
+	return app.ReadPixels()
+}
+
+// Write an image to a PNG file.
+func SavePNG(fn string, img image.Image) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", fn, err)
+	}
+	return f.Close()
+}
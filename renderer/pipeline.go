@@ -0,0 +1,770 @@
+package renderer
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Pipeline owns a render pass, the graphics pipelines used in it and the
+// framebuffers and command buffers for drawing into a swapchain. The
+// swapchain isn't owned, several pipelines can draw into one.
+type Pipeline struct {
+	Swapchain             *Swapchain
+	SwapchainFramebuffers []vk.Framebuffer
+
+	RenderPass           vk.RenderPass
+	renderPassFormat     vk.Format
+	DepthFormat          vk.Format // FormatUndefined without a depth buffer.
+	DepthImage           vk.Image
+	DepthImageView       vk.ImageView
+	depthAllocation      MemoryAllocation
+	Samples              vk.SampleCountFlagBits // Resolved into the swapchain image when more than 1.
+	ColorImage           vk.Image               // Multisampled color, null with 1 sample.
+	ColorImageView       vk.ImageView
+	colorAllocation      MemoryAllocation
+	DescriptorBindings   []DescriptorBinding
+	DescriptorSetLayouts []vk.DescriptorSetLayout
+	PushConstantRanges   []vk.PushConstantRange
+	PipelineLayout       vk.PipelineLayout
+	Pipelines            []vk.Pipeline
+
+	graphicsCommandPool    vk.CommandPool
+	GraphicsCommandBuffers []vk.CommandBuffer
+
+	allocator *Allocator
+}
+
+// A loaded shader module and what was reflected from it.
+type Shader struct {
+	File               string
+	Module             vk.ShaderModule
+	EntryPoint         EntryPoint
+	Reflection         *Reflection
+	DescriptorBindings []DescriptorBinding
+	PushConstantRanges []vk.PushConstantRange
+}
+
+// NewPipeline creates the render pass and graphics pipelines for the
+// application, and the framebuffers for drawing them into the swapchain.
+func NewPipeline(app *TriangleApplication, swapchain *Swapchain) (*Pipeline, error) {
+	// Create the result object. Handles are filled in as they are created so
+	// a failure part way through can release everything created so far.
+	pipeline := &Pipeline{
+		Swapchain:           swapchain,
+		graphicsCommandPool: app.graphicsCommandPool,
+		allocator:           app.allocator,
+	}
+
+	// Steps.
+	steps := []func() error{
+		func() error { return pipeline.createRenderPass(app) },
+		func() error { return pipeline.createPipelines(app) },
+		func() error { return pipeline.createFramebuffers(app) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			pipeline.Cleanup(app.device)
+			return nil, err
+		}
+	}
+
+	// Return the pipeline
+	return pipeline, nil
+}
+
+// RecreateFramebuffers creates the framebuffers and command buffers for the
+// swapchain after it has been recreated. The viewport and scissor are
+// dynamic, so the render pass and pipelines are kept unless the image format
+// changed.
+func (pipeline *Pipeline) RecreateFramebuffers(app *TriangleApplication) error {
+	pipeline.CleanupFramebuffers(app.device)
+
+	// A new format needs a new render pass, and pipelines for it.
+	if pipeline.Swapchain.ImageFormat != pipeline.renderPassFormat {
+		pipeline.cleanupPipelines(app.device)
+		if err := pipeline.createRenderPass(app); err != nil {
+			return err
+		}
+		if err := pipeline.createPipelines(app); err != nil {
+			return err
+		}
+	}
+
+	// Create the framebuffers.
+	return pipeline.createFramebuffers(app)
+}
+
+// Create the render pass for the swapchain format.
+func (pipeline *Pipeline) createRenderPass(app *TriangleApplication) error {
+	var err error
+
+	// Pick the depth format.
+	pipeline.DepthFormat = vk.FormatUndefined
+	if len(app.DepthFormats) > 0 {
+		pipeline.DepthFormat, err = app.physicalDevice.FindDepthFormat(app.DepthFormats)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pick the sample count.
+	pipeline.Samples = vk.SampleCount1Bit
+	if app.Samples > vk.SampleCount1Bit {
+		supported := app.physicalDevice.FramebufferSampleCounts(pipeline.DepthFormat != vk.FormatUndefined)
+		pipeline.Samples = ClampSampleCount(app.Samples, supported)
+		if pipeline.Samples != app.Samples {
+			fmt.Printf("%d samples requested, the device supports %d\n", app.Samples, pipeline.Samples)
+		}
+	}
+	multisampled := pipeline.Samples != vk.SampleCount1Bit
+
+	// Create the render pass.
+	pipeline.RenderPass, err = func() (vk.RenderPass, error) {
+		// Offscreen images are copied out instead of presented.
+		finalLayout := vk.ImageLayoutPresentSrc
+		if pipeline.Swapchain.Offscreen() {
+			finalLayout = vk.ImageLayoutTransferSrcOptimal
+		}
+
+		// The color attachment. When multisampled it is only needed until
+		// it is resolved into the swapchain image.
+		colorAttachment := vk.AttachmentDescription{
+			Format:         pipeline.Swapchain.ImageFormat,
+			Samples:        pipeline.Samples,
+			LoadOp:         vk.AttachmentLoadOpClear,
+			StoreOp:        vk.AttachmentStoreOpStore,
+			StencilLoadOp:  vk.AttachmentLoadOpDontCare,
+			StencilStoreOp: vk.AttachmentStoreOpDontCare,
+			InitialLayout:  vk.ImageLayoutUndefined,
+			FinalLayout:    finalLayout,
+		}
+		if multisampled {
+			colorAttachment.StoreOp = vk.AttachmentStoreOpDontCare
+			colorAttachment.FinalLayout = vk.ImageLayoutColorAttachmentOptimal
+		}
+		attachments := []vk.AttachmentDescription{colorAttachment}
+		subpass := vk.SubpassDescription{
+			PipelineBindPoint:    vk.PipelineBindPointGraphics,
+			ColorAttachmentCount: 1,
+			PColorAttachments: []vk.AttachmentReference{
+				vk.AttachmentReference{
+					Attachment: 0,
+					Layout:     vk.ImageLayoutColorAttachmentOptimal,
+				},
+			},
+		}
+		dependency := vk.SubpassDependency{
+			SrcSubpass:    vk.SubpassExternal,
+			SrcStageMask:  vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+			DstStageMask:  vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+			DstAccessMask: vk.AccessFlags(vk.AccessColorAttachmentWriteBit),
+		}
+
+		// The depth attachment. Its contents are only needed during the
+		// render pass.
+		if pipeline.DepthFormat != vk.FormatUndefined {
+			stencilLoadOp := vk.AttachmentLoadOpDontCare
+			if HasStencilComponent(pipeline.DepthFormat) {
+				stencilLoadOp = vk.AttachmentLoadOpClear
+			}
+			subpass.PDepthStencilAttachment = &vk.AttachmentReference{
+				Attachment: uint32(len(attachments)),
+				Layout:     vk.ImageLayoutDepthStencilAttachmentOptimal,
+			}
+			attachments = append(attachments, vk.AttachmentDescription{
+				Format:         pipeline.DepthFormat,
+				Samples:        pipeline.Samples,
+				LoadOp:         vk.AttachmentLoadOpClear,
+				StoreOp:        vk.AttachmentStoreOpDontCare,
+				StencilLoadOp:  stencilLoadOp,
+				StencilStoreOp: vk.AttachmentStoreOpDontCare,
+				InitialLayout:  vk.ImageLayoutUndefined,
+				FinalLayout:    vk.ImageLayoutDepthStencilAttachmentOptimal,
+			})
+
+			// The previous frame's depth tests must finish before the
+			// depth image is cleared.
+			dependency.SrcStageMask |= vk.PipelineStageFlags(vk.PipelineStageLateFragmentTestsBit)
+			dependency.DstStageMask |= vk.PipelineStageFlags(vk.PipelineStageEarlyFragmentTestsBit)
+			dependency.SrcAccessMask |= vk.AccessFlags(vk.AccessDepthStencilAttachmentWriteBit)
+			dependency.DstAccessMask |= vk.AccessFlags(vk.AccessDepthStencilAttachmentWriteBit)
+		}
+
+		// The resolve attachment is the swapchain image.
+		if multisampled {
+			// Every frame draws into the same multisampled color image, so
+			// the previous frame's writes must finish before it is cleared.
+			dependency.SrcAccessMask |= vk.AccessFlags(vk.AccessColorAttachmentWriteBit)
+
+			subpass.PResolveAttachments = []vk.AttachmentReference{
+				vk.AttachmentReference{
+					Attachment: uint32(len(attachments)),
+					Layout:     vk.ImageLayoutColorAttachmentOptimal,
+				},
+			}
+			attachments = append(attachments, vk.AttachmentDescription{
+				Format:         pipeline.Swapchain.ImageFormat,
+				Samples:        vk.SampleCount1Bit,
+				LoadOp:         vk.AttachmentLoadOpDontCare,
+				StoreOp:        vk.AttachmentStoreOpStore,
+				StencilLoadOp:  vk.AttachmentLoadOpDontCare,
+				StencilStoreOp: vk.AttachmentStoreOpDontCare,
+				InitialLayout:  vk.ImageLayoutUndefined,
+				FinalLayout:    finalLayout,
+			})
+		}
+
+		// Create the info object.
+		renderPassInfo := vk.RenderPassCreateInfo{
+			SType:           vk.StructureTypeRenderPassCreateInfo,
+			AttachmentCount: uint32(len(attachments)),
+			PAttachments:    attachments,
+			SubpassCount:    1,
+			PSubpasses:      []vk.SubpassDescription{subpass},
+			DependencyCount: 1,
+			PDependencies:   []vk.SubpassDependency{dependency},
+		}
+
+		// Create the result object.
+		var renderPass vk.RenderPass
+
+		// Call the Vulkan function.
+		err := CheckResultInfo("vkCreateRenderPass", vk.CreateRenderPass(app.device, &renderPassInfo, nil, &renderPass),
+			fmt.Sprintf("format=%d depthFormat=%d samples=%d", pipeline.Swapchain.ImageFormat, pipeline.DepthFormat, pipeline.Samples))
+
+		// return the render pass
+		return renderPass, err
+	}()
+	pipeline.renderPassFormat = pipeline.Swapchain.ImageFormat
+	return err
+}
+
+// Create the multisampled color and depth images, and the framebuffers and
+// command buffers, one per swapchain image.
+func (pipeline *Pipeline) createFramebuffers(app *TriangleApplication) error {
+	var err error
+
+	// Create the multisampled color image. Like the depth image, one is
+	// enough for every framebuffer, since the render pass dependency keeps
+	// frames from using it at the same time.
+	if pipeline.Samples != vk.SampleCount1Bit {
+		pipeline.ColorImage, pipeline.colorAllocation, pipeline.ColorImageView, err = newAttachmentImage(pipeline.allocator,
+			pipeline.Swapchain.ImageFormat,
+			pipeline.Swapchain.Extent,
+			pipeline.Samples,
+			vk.ImageUsageFlags(vk.ImageUsageColorAttachmentBit|vk.ImageUsageTransientAttachmentBit),
+			vk.ImageAspectFlags(vk.ImageAspectColorBit))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the depth image.
+	if pipeline.DepthFormat != vk.FormatUndefined {
+		pipeline.DepthImage, pipeline.depthAllocation, pipeline.DepthImageView, err = newAttachmentImage(pipeline.allocator,
+			pipeline.DepthFormat,
+			pipeline.Swapchain.Extent,
+			pipeline.Samples,
+			vk.ImageUsageFlags(vk.ImageUsageDepthStencilAttachmentBit),
+			depthAspectMask(pipeline.DepthFormat))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the framebuffers.
+	pipeline.SwapchainFramebuffers, err = func() ([]vk.Framebuffer, error) {
+		// Create the result object.
+		buffers := make([]vk.Framebuffer, len(pipeline.Swapchain.ImageViews))
+
+		// Create one framebuffer per image view.
+		for k, imgView := range pipeline.Swapchain.ImageViews {
+			// The attachments, in render pass order.
+			attachments := []vk.ImageView{imgView}
+			if pipeline.Samples != vk.SampleCount1Bit {
+				attachments[0] = pipeline.ColorImageView
+			}
+			if pipeline.DepthFormat != vk.FormatUndefined {
+				attachments = append(attachments, pipeline.DepthImageView)
+			}
+			if pipeline.Samples != vk.SampleCount1Bit {
+				attachments = append(attachments, imgView)
+			}
+
+			// Create the info object.
+			bufferInfo := vk.FramebufferCreateInfo{
+				SType:           vk.StructureTypeFramebufferCreateInfo,
+				RenderPass:      pipeline.RenderPass,
+				AttachmentCount: uint32(len(attachments)),
+				PAttachments:    attachments,
+				Width:           pipeline.Swapchain.Extent.Width,
+				Height:          pipeline.Swapchain.Extent.Height,
+				Layers:          1,
+			}
+
+			// Call the Vulkan function.
+			err := CheckResultInfo("vkCreateFramebuffer", vk.CreateFramebuffer(app.device, &bufferInfo, nil, &buffers[k]),
+				fmt.Sprintf("framebuffer=%d extent=%dx%d", k, bufferInfo.Width, bufferInfo.Height))
+			if err != nil {
+				return buffers, err
+			}
+		}
+
+		// Return the framebuffers.
+		return buffers, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	pipeline.GraphicsCommandBuffers, err = func() ([]vk.CommandBuffer, error) {
+		// Create the info object.
+		buffersInfo := vk.CommandBufferAllocateInfo{
+			SType:              vk.StructureTypeCommandBufferAllocateInfo,
+			CommandPool:        app.graphicsCommandPool,
+			Level:              vk.CommandBufferLevelPrimary,
+			CommandBufferCount: uint32(len(pipeline.SwapchainFramebuffers)),
+		}
+
+		// Create the result object.
+		buffers := make([]vk.CommandBuffer, buffersInfo.CommandBufferCount)
+
+		// Call the vulkan function.
+		err := CheckResultInfo("vkAllocateCommandBuffers", vk.AllocateCommandBuffers(app.device, &buffersInfo, buffers),
+			fmt.Sprintf("count=%d", buffersInfo.CommandBufferCount))
+
+		// Return the command buffers.
+		return buffers, err
+	}()
+	return err
+}
+
+// Load the shaders and create the layouts and graphics pipelines.
+func (pipeline *Pipeline) createPipelines(app *TriangleApplication) error {
+	// The pipeline descriptions, the default triangle if there are none.
+	descs := app.PipelineDescs
+	if len(descs) == 0 {
+		desc := NewGraphicsPipelineDesc()
+		if pipeline.DepthFormat != vk.FormatUndefined {
+			desc = desc.WithDepth(true, true, vk.CompareOpLess)
+		}
+		descs = []GraphicsPipelineDesc{desc}
+	}
+
+	// Pipelines rasterize with the render pass's sample count. Descriptions
+	// left at 1 sample follow it, others must match it.
+	descs = append([]GraphicsPipelineDesc{}, descs...)
+	for k, desc := range descs {
+		if desc.Samples == vk.SampleCount1Bit || desc.Samples == 0 {
+			descs[k].Samples = pipeline.Samples
+		} else if desc.Samples != pipeline.Samples {
+			return fmt.Errorf("pipeline %s uses %d samples, but the render pass has %d",
+				OrDefault(desc.Name, fmt.Sprintf("%d", k)), desc.Samples, pipeline.Samples)
+		}
+	}
+
+	// Depth and stencil tests need somewhere to test against, and line and
+	// point rasterization, wide lines and sample shading need device
+	// features.
+	for k, desc := range descs {
+		name := OrDefault(desc.Name, fmt.Sprintf("%d", k))
+		if (desc.DepthTest || desc.DepthWrite) && pipeline.DepthFormat == vk.FormatUndefined {
+			return fmt.Errorf("pipeline %s uses depth, but the application has no depth buffer", name)
+		}
+		if desc.StencilTest && !HasStencilComponent(pipeline.DepthFormat) {
+			return fmt.Errorf("pipeline %s uses stencil, but the application has no stencil buffer", name)
+		}
+		if err := desc.CheckFeatures(app.enabledFeatures); err != nil {
+			return fmt.Errorf("pipeline %s: %w", name, err)
+		}
+	}
+
+	// Load the shaders. The modules are only needed until the pipelines are
+	// created. Shaders shared by several descriptions are loaded once.
+	shaders, descShaders, err := func() ([]Shader, [][]int, error) {
+		// Function for loading a shader and reflecting its entry point.
+		loadShaderModule := func(fn, name string, model ExecutionModel) (Shader, error) {
+			// load the shader bytes
+			shaderBytes, err := ioutil.ReadFile(fn)
+			if err != nil {
+				return Shader{}, fmt.Errorf("failed to read shader: %w", err)
+			}
+			shaderCode, err := ParseSPIRV(shaderBytes)
+			if err != nil {
+				return Shader{}, fmt.Errorf("%s: %w", fn, err)
+			}
+			shaderWords := shaderCode.Words
+
+			// Reflect the entry point.
+			refl, err := Reflect(shaderCode)
+			if err != nil {
+				return Shader{}, fmt.Errorf("%s: %w", fn, err)
+			}
+			entryPoint, err := refl.FindEntryPoint(name, model)
+			if err != nil {
+				return Shader{}, fmt.Errorf("%s: %w", fn, err)
+			}
+
+			// Reflect the resources.
+			bindings, err := refl.DescriptorBindings(entryPoint)
+			if err != nil {
+				return Shader{}, fmt.Errorf("%s: %w", fn, err)
+			}
+
+			// Create the info object.
+			shaderInfo := vk.ShaderModuleCreateInfo{
+				SType:    vk.StructureTypeShaderModuleCreateInfo,
+				CodeSize: shaderWords.Sizeof(),
+				PCode:    []uint32(shaderWords),
+			}
+
+			// Create the result object.
+			shader := Shader{
+				File:               fn,
+				EntryPoint:         entryPoint,
+				Reflection:         refl,
+				DescriptorBindings: bindings,
+				PushConstantRanges: refl.PushConstantRanges(entryPoint),
+			}
+
+			// Call the Vulkan function.
+			err = CheckResultInfo("vkCreateShaderModule", vk.CreateShaderModule(app.device, &shaderInfo, nil, &shader.Module),
+				fmt.Sprintf("file=%s codeSize=%d", fn, shaderInfo.CodeSize))
+
+			// return the shader
+			return shader, err
+		}
+
+		// Create the result objects.
+		type key struct {
+			fn, name string
+			model    ExecutionModel
+		}
+		shaders := make([]Shader, 0, 2)
+		index := make(map[key]int)
+		descShaders := make([][]int, len(descs))
+
+		// Load the stages of each description in pipeline order.
+		for k, desc := range descs {
+			for _, stage := range []key{
+				{OrDefault(desc.VertexShaderFile, OrDefault(app.VertexShaderFile, DefaultVertexShaderFile)), desc.VertexEntryPoint, ExecutionModelVertex},
+				{OrDefault(desc.FragmentShaderFile, OrDefault(app.FragmentShaderFile, DefaultFragmentShaderFile)), desc.FragmentEntryPoint, ExecutionModelFragment},
+			} {
+				h, ok := index[stage]
+				if !ok {
+					shader, err := loadShaderModule(stage.fn, stage.name, stage.model)
+					if err != nil {
+						return shaders, descShaders, err
+					}
+					h = len(shaders)
+					index[stage] = h
+					shaders = append(shaders, shader)
+				}
+				descShaders[k] = append(descShaders[k], h)
+			}
+		}
+
+		// Return the shaders.
+		return shaders, descShaders, nil
+	}()
+	defer func() {
+		for _, shader := range shaders {
+			vk.DestroyShaderModule(app.device, shader.Module, nil)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Check each stage reads what the stage before it writes.
+	for _, stages := range descShaders {
+		for k := 1; k < len(stages); k++ {
+			producer, consumer := shaders[stages[k-1]], shaders[stages[k]]
+			err := CheckStageInterface(producer.EntryPoint, consumer.EntryPoint)
+			if err != nil {
+				return fmt.Errorf("%s -> %s: %w", producer.File, consumer.File, err)
+			}
+		}
+	}
+
+	// Check the vertex input feeds the vertex shader, with formats the
+	// device supports.
+	for k, desc := range descs {
+		vertex := shaders[descShaders[k][0]]
+		err := CheckVertexInput(vertex.EntryPoint, desc.VertexBindings, desc.VertexAttributes)
+		if err == nil {
+			err = app.physicalDevice.CheckVertexFormats(desc.VertexAttributes)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: pipeline %s: %w", vertex.File, OrDefault(desc.Name, fmt.Sprintf("%d", k)), err)
+		}
+	}
+
+	// Merge the shader resources.
+	pipeline.DescriptorBindings, pipeline.PushConstantRanges, err = func() ([]DescriptorBinding, []vk.PushConstantRange, error) {
+		stageBindings := make([][]DescriptorBinding, len(shaders))
+		stageRanges := make([][]vk.PushConstantRange, len(shaders))
+		for k, shader := range shaders {
+			stageBindings[k] = shader.DescriptorBindings
+			stageRanges[k] = shader.PushConstantRanges
+		}
+		bindings, err := MergeDescriptorBindings(stageBindings...)
+		return bindings, MergePushConstantRanges(stageRanges...), err
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Uniform buffers the descriptions list are bound with dynamic offsets.
+	for k, desc := range descs {
+		for _, sb := range desc.DynamicUniformBuffers {
+			found := false
+			for h, binding := range pipeline.DescriptorBindings {
+				if binding.Set == sb.Set && binding.Binding == sb.Binding &&
+					(binding.DescriptorType == vk.DescriptorTypeUniformBuffer ||
+						binding.DescriptorType == vk.DescriptorTypeUniformBufferDynamic) {
+					pipeline.DescriptorBindings[h].DescriptorType = vk.DescriptorTypeUniformBufferDynamic
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("pipeline %s: dynamic uniform buffer set=%d binding=%d is not a uniform buffer in the shaders",
+					OrDefault(desc.Name, fmt.Sprintf("%d", k)), sb.Set, sb.Binding)
+			}
+		}
+	}
+
+	// Create the descriptor set layouts.
+	pipeline.DescriptorSetLayouts, err = func() ([]vk.DescriptorSetLayout, error) {
+		sets := DescriptorSetLayoutBindings(pipeline.DescriptorBindings)
+
+		// Create the result object.
+		layouts := make([]vk.DescriptorSetLayout, 0, len(sets))
+
+		// Create one layout per set.
+		for k, bindings := range sets {
+			// Create the info object.
+			layoutInfo := vk.DescriptorSetLayoutCreateInfo{
+				SType:        vk.StructureTypeDescriptorSetLayoutCreateInfo,
+				BindingCount: uint32(len(bindings)),
+				PBindings:    bindings,
+			}
+
+			// Call the Vulkan function.
+			var layout vk.DescriptorSetLayout
+			err := CheckResultInfo("vkCreateDescriptorSetLayout", vk.CreateDescriptorSetLayout(app.device, &layoutInfo, nil, &layout),
+				fmt.Sprintf("set=%d bindings=%d", k, layoutInfo.BindingCount))
+			if err != nil {
+				return layouts, err
+			}
+			layouts = append(layouts, layout)
+		}
+
+		// Return the layouts.
+		return layouts, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Create the pipeline layout.
+	pipeline.PipelineLayout, err = func() (vk.PipelineLayout, error) {
+		// Create the info object.
+		layoutInfo := vk.PipelineLayoutCreateInfo{
+			SType:                  vk.StructureTypePipelineLayoutCreateInfo,
+			SetLayoutCount:         uint32(len(pipeline.DescriptorSetLayouts)),
+			PSetLayouts:            pipeline.DescriptorSetLayouts,
+			PushConstantRangeCount: uint32(len(pipeline.PushConstantRanges)),
+			PPushConstantRanges:    pipeline.PushConstantRanges,
+		}
+
+		// Create the result object.
+		var layout vk.PipelineLayout
+
+		// Call the Vulkan function.
+		err := CheckResultInfo("vkCreatePipelineLayout", vk.CreatePipelineLayout(app.device, &layoutInfo, nil, &layout),
+			fmt.Sprintf("sets=%d pushConstantRanges=%d", layoutInfo.SetLayoutCount, layoutInfo.PushConstantRangeCount))
+
+		// Return the layout.
+		return layout, err
+	}()
+	if err != nil {
+		return err
+	}
+
+	// Create the pipelines.
+	pipeline.Pipelines, err = func() ([]vk.Pipeline, error) {
+		// Create the info objects, one per description.
+		pipelineInfos := make([]vk.GraphicsPipelineCreateInfo, len(descs))
+		stageCount := 0
+		for k, desc := range descs {
+			// Create the ShaderStage info objects.
+			shaderStages := make([]vk.PipelineShaderStageCreateInfo, len(descShaders[k]))
+			for h, index := range descShaders[k] {
+				shaderStages[h] = vk.PipelineShaderStageCreateInfo{
+					SType:  vk.StructureTypePipelineShaderStageCreateInfo,
+					Stage:  shaders[index].EntryPoint.Model.ShaderStage(),
+					Module: shaders[index].Module,
+					PName:  ToCString(shaders[index].EntryPoint.Name),
+				}
+			}
+			stageCount += len(shaderStages)
+
+			pipelineInfos[k] = desc.CreateInfo(shaderStages, pipeline.PipelineLayout, pipeline.RenderPass, 0)
+		}
+
+		// Create the result object.
+		pipelines := make([]vk.Pipeline, len(pipelineInfos))
+
+		// Use the application's cache, if it has one.
+		cache := vk.PipelineCache(vk.NullHandle)
+		if app.pipelineCache != nil {
+			cache = app.pipelineCache.Handle
+		}
+
+		// Call the Vulkan function.
+		err = CheckResultInfo("vkCreateGraphicsPipelines", vk.CreateGraphicsPipelines(app.device,
+			cache,
+			uint32(len(pipelineInfos)),
+			pipelineInfos,
+			nil,
+			pipelines),
+			fmt.Sprintf("pipelines=%d stages=%d", len(pipelineInfos), stageCount))
+
+		// Return the pipelines.
+		return pipelines, err
+	}()
+	return err
+}
+
+// Record the command buffer for a swapchain image. The render pass is begun
+// and the first pipeline bound before record, the OnRecord hook, is called
+// to add the draws. Its error is wrapped, Vulkan errors are returned as
+// they are.
+func (pipeline *Pipeline) RecordCommandBuffer(imageIndex uint32, record func(vk.CommandBuffer, uint32) error) error {
+	cmdBuffer := pipeline.GraphicsCommandBuffers[imageIndex]
+
+	// Throw away the previous recording.
+	err := CheckResult("vkResetCommandBuffer", vk.ResetCommandBuffer(cmdBuffer, 0))
+	if err != nil {
+		return err
+	}
+
+	// Start recording
+	err = CheckResult("vkBeginCommandBuffer", vk.BeginCommandBuffer(cmdBuffer, &vk.CommandBufferBeginInfo{
+		SType: vk.StructureTypeCommandBufferBeginInfo,
+	}))
+	if err != nil {
+		return err
+	}
+
+	// Clear color to black and depth to the far plane.
+	clearValues := []vk.ClearValue{
+		vk.NewClearValue([]float32{0.0, 0.0, 0.0, 1.0}),
+	}
+	if pipeline.DepthFormat != vk.FormatUndefined {
+		clearValues = append(clearValues, vk.NewClearDepthStencil(1.0, 0))
+	}
+
+	// Create the info object.
+	beginInfo := vk.RenderPassBeginInfo{
+		SType:       vk.StructureTypeRenderPassBeginInfo,
+		RenderPass:  pipeline.RenderPass,
+		Framebuffer: pipeline.SwapchainFramebuffers[imageIndex],
+		RenderArea: vk.Rect2D{
+			Offset: vk.Offset2D{X: 0, Y: 0},
+			Extent: pipeline.Swapchain.Extent,
+		},
+		ClearValueCount: uint32(len(clearValues)),
+		PClearValues:    clearValues,
+	}
+
+	// Call the Vulkan function.
+	vk.CmdBeginRenderPass(cmdBuffer, &beginInfo, vk.SubpassContentsInline)
+
+	// Bind the buffer to the graphics point in the pipeline.
+	vk.CmdBindPipeline(cmdBuffer, vk.PipelineBindPointGraphics, pipeline.Pipelines[0])
+
+	// Cover the whole image, the viewport and scissor are dynamic.
+	vk.CmdSetViewport(cmdBuffer, 0, 1, []vk.Viewport{
+		vk.Viewport{
+			Width:    float32(pipeline.Swapchain.Extent.Width),
+			Height:   float32(pipeline.Swapchain.Extent.Height),
+			MaxDepth: 1.0,
+		},
+	})
+	vk.CmdSetScissor(cmdBuffer, 0, 1, []vk.Rect2D{
+		vk.Rect2D{
+			Offset: vk.Offset2D{},
+			Extent: pipeline.Swapchain.Extent,
+		},
+	})
+
+	// Draw
+	recordErr := record(cmdBuffer, imageIndex)
+
+	// End the render pass
+	vk.CmdEndRenderPass(cmdBuffer)
+
+	// Stop recording
+	err = CheckResult("vkEndCommandBuffer", vk.EndCommandBuffer(cmdBuffer))
+	if recordErr != nil {
+		return fmt.Errorf("OnRecord hook failed: %w", recordErr)
+	}
+	return err
+}
+
+func (pipeline *Pipeline) Cleanup(device vk.Device) {
+	vk.DeviceWaitIdle(device)
+	pipeline.CleanupFramebuffers(device)
+	pipeline.cleanupPipelines(device)
+}
+
+// CleanupFramebuffers destroys the objects that depend on the swapchain
+// images and extent. It must be called before the swapchain is recreated.
+func (pipeline *Pipeline) CleanupFramebuffers(device vk.Device) {
+	for _, buffer := range pipeline.SwapchainFramebuffers {
+		vk.DestroyFramebuffer(device, buffer, nil)
+	}
+	pipeline.SwapchainFramebuffers = nil
+
+	if len(pipeline.GraphicsCommandBuffers) > 0 {
+		vk.FreeCommandBuffers(device,
+			pipeline.graphicsCommandPool,
+			uint32(len(pipeline.GraphicsCommandBuffers)),
+			pipeline.GraphicsCommandBuffers)
+	}
+	pipeline.GraphicsCommandBuffers = nil
+
+	vk.DestroyImageView(device, pipeline.DepthImageView, nil)
+	vk.DestroyImage(device, pipeline.DepthImage, nil)
+	pipeline.allocator.Free(pipeline.depthAllocation)
+	pipeline.DepthImageView = vk.ImageView(vk.NullHandle)
+	pipeline.DepthImage = vk.Image(vk.NullHandle)
+	pipeline.depthAllocation = MemoryAllocation{}
+
+	vk.DestroyImageView(device, pipeline.ColorImageView, nil)
+	vk.DestroyImage(device, pipeline.ColorImage, nil)
+	pipeline.allocator.Free(pipeline.colorAllocation)
+	pipeline.ColorImageView = vk.ImageView(vk.NullHandle)
+	pipeline.ColorImage = vk.Image(vk.NullHandle)
+	pipeline.colorAllocation = MemoryAllocation{}
+}
+
+// Destroy the render pass, layouts and pipelines.
+func (pipeline *Pipeline) cleanupPipelines(device vk.Device) {
+	for _, pl := range pipeline.Pipelines {
+		vk.DestroyPipeline(device, pl, nil)
+	}
+	pipeline.Pipelines = nil
+	vk.DestroyPipelineLayout(device, pipeline.PipelineLayout, nil)
+	pipeline.PipelineLayout = vk.PipelineLayout(vk.NullHandle)
+	for _, layout := range pipeline.DescriptorSetLayouts {
+		vk.DestroyDescriptorSetLayout(device, layout, nil)
+	}
+	pipeline.DescriptorSetLayouts = nil
+	vk.DestroyRenderPass(device, pipeline.RenderPass, nil)
+	pipeline.RenderPass = vk.RenderPass(vk.NullHandle)
+}
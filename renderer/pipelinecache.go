@@ -0,0 +1,170 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Pipeline cache header values. The header is always little-endian.
+const (
+	pipelineCacheHeaderSize       = 32
+	pipelineCacheHeaderVersionOne = 1
+)
+
+// PipelineCache is a Vulkan pipeline cache backed by a file, so pipelines
+// compiled by one run are reused by the next.
+type PipelineCache struct {
+	Handle vk.PipelineCache
+	File   string // Empty if the cache is only kept in memory.
+	Loaded bool   // The file was valid and its blob was given to the driver.
+
+	device vk.Device
+}
+
+// ValidatePipelineCacheHeader reports why a cache blob can't be used by the
+// device, or nil if it can.
+func ValidatePipelineCacheHeader(data []byte, props vk.PhysicalDeviceProperties) error {
+	// Length checks.
+	if len(data) < pipelineCacheHeaderSize {
+		return fmt.Errorf("pipeline cache: length %d is shorter than the %d byte header", len(data), pipelineCacheHeaderSize)
+	}
+	headerSize := binary.LittleEndian.Uint32(data[0:])
+	if headerSize < pipelineCacheHeaderSize || int(headerSize) > len(data) {
+		return fmt.Errorf("pipeline cache: bad header size %d for a %d byte blob", headerSize, len(data))
+	}
+
+	// Header fields.
+	version := binary.LittleEndian.Uint32(data[4:])
+	vendorID := binary.LittleEndian.Uint32(data[8:])
+	deviceID := binary.LittleEndian.Uint32(data[12:])
+	uuid := data[16:32]
+	if version != pipelineCacheHeaderVersionOne {
+		return fmt.Errorf("pipeline cache: unsupported header version %d", version)
+	}
+	if vendorID != props.VendorID || deviceID != props.DeviceID {
+		return fmt.Errorf("pipeline cache: written by device %#04x:%#04x, expected %#04x:%#04x",
+			vendorID, deviceID,
+			props.VendorID, props.DeviceID)
+	}
+	if !bytes.Equal(uuid, props.PipelineCacheUUID[:]) {
+		return fmt.Errorf("pipeline cache: UUID %x doesn't match the driver's %x", uuid, props.PipelineCacheUUID[:])
+	}
+	return nil
+}
+
+// NewPipelineCache creates a pipeline cache, seeded from a file in dir if it
+// holds a blob the device can use. Stale or foreign files are ignored and
+// replaced on Save. An empty dir keeps the cache in memory.
+func NewPipelineCache(device vk.Device, phyDev PhysicalDevice, dir string) (*PipelineCache, error) {
+	// Create the result object.
+	props := phyDev.Properties
+	cache := &PipelineCache{
+		device: device,
+	}
+	if dir != "" {
+		cache.File = filepath.Join(dir, fmt.Sprintf("pipelines-%04x-%04x.bin", props.VendorID, props.DeviceID))
+	}
+
+	// Load the blob.
+	var initialData []byte
+	if cache.File != "" {
+		data, err := ioutil.ReadFile(cache.File)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := ValidatePipelineCacheHeader(data, props); err != nil {
+				fmt.Printf("Discarding %s: %v\n", cache.File, err)
+			} else {
+				initialData = data
+			}
+		}
+	}
+
+	// Create the info object.
+	cacheInfo := vk.PipelineCacheCreateInfo{
+		SType:           vk.StructureTypePipelineCacheCreateInfo,
+		InitialDataSize: uint(len(initialData)),
+	}
+	if len(initialData) > 0 {
+		cacheInfo.PInitialData = unsafe.Pointer(&initialData[0])
+	}
+
+	// Call the Vulkan function.
+	err := CheckResultInfo("vkCreatePipelineCache", vk.CreatePipelineCache(device, &cacheInfo, nil, &cache.Handle),
+		fmt.Sprintf("file=%s initialDataSize=%d", cache.File, cacheInfo.InitialDataSize))
+	if err != nil {
+		return nil, err
+	}
+	cache.Loaded = len(initialData) > 0
+	return cache, nil
+}
+
+// Save writes the cache to its file. The blob is written to a temporary file
+// that replaces the old one, so a crash never leaves a partial cache.
+func (cache *PipelineCache) Save() error {
+	if cache.File == "" {
+		return nil
+	}
+
+	// Get the size of the blob.
+	var size uint
+	err := CheckResult("vkGetPipelineCacheData", vk.GetPipelineCacheData(cache.device, cache.Handle, &size, nil))
+	if err != nil || size == 0 {
+		return err
+	}
+
+	// Get the blob.
+	data := make([]byte, size)
+	err = CheckResult("vkGetPipelineCacheData", vk.GetPipelineCacheData(cache.device, cache.Handle, &size, unsafe.Pointer(&data[0])))
+	if err != nil {
+		return err
+	}
+	data = data[:size]
+
+	// Write it next to the file and move it into place.
+	dir := filepath.Dir(cache.File)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, filepath.Base(cache.File)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	// Flush it to disk before the rename, or a crash could leave an empty
+	// file in its place.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), cache.File); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Destroy the cache. Save should be called first to keep its contents.
+func (cache *PipelineCache) Cleanup() {
+	vk.DestroyPipelineCache(cache.device, cache.Handle, nil)
+	cache.Handle = vk.PipelineCache(vk.NullHandle)
+}
@@ -0,0 +1,89 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestValidatePipelineCacheHeader(t *testing.T) {
+	props := vk.PhysicalDeviceProperties{VendorID: 0x10de, DeviceID: 0x2204}
+	for k := range props.PipelineCacheUUID {
+		props.PipelineCacheUUID[k] = byte(k + 1)
+	}
+
+	// A blob with a valid header and some data after it, changed by edit.
+	blob := func(edit func(b []byte)) []byte {
+		b := make([]byte, 40)
+		binary.LittleEndian.PutUint32(b[0:], pipelineCacheHeaderSize)
+		binary.LittleEndian.PutUint32(b[4:], pipelineCacheHeaderVersionOne)
+		binary.LittleEndian.PutUint32(b[8:], props.VendorID)
+		binary.LittleEndian.PutUint32(b[12:], props.DeviceID)
+		copy(b[16:32], props.PipelineCacheUUID[:])
+		if edit != nil {
+			edit(b)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{name: "valid", data: blob(nil)},
+		{name: "header only", data: blob(nil)[:32]},
+		{
+			name: "longer header",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[0:], 36) }),
+		},
+		{name: "empty", data: nil, err: "length 0 is shorter than the 32 byte header"},
+		{name: "short", data: blob(nil)[:31], err: "length 31 is shorter than the 32 byte header"},
+		{
+			name: "header size too small",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[0:], 16) }),
+			err:  "bad header size 16 for a 40 byte blob",
+		},
+		{
+			name: "header size past the end",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[0:], 41) }),
+			err:  "bad header size 41 for a 40 byte blob",
+		},
+		{
+			name: "wrong version",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[4:], 2) }),
+			err:  "unsupported header version 2",
+		},
+		{
+			name: "other vendor",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[8:], 0x1002) }),
+			err:  "written by device 0x1002:0x2204, expected 0x10de:0x2204",
+		},
+		{
+			name: "other device",
+			data: blob(func(b []byte) { binary.LittleEndian.PutUint32(b[12:], 0x2206) }),
+			err:  "written by device 0x10de:0x2206, expected 0x10de:0x2204",
+		},
+		{
+			name: "other driver",
+			data: blob(func(b []byte) { b[31] ^= 0xff }),
+			err:  "UUID 0102030405060708090a0b0c0d0e0fef doesn't match the driver's 0102030405060708090a0b0c0d0e0f10",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePipelineCacheHeader(test.data, props)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
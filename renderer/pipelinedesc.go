@@ -0,0 +1,326 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Blend Mode
+type BlendMode int
+
+const (
+	BlendNone BlendMode = iota
+	BlendAlpha
+	BlendAdditive
+	BlendPremultiplied
+)
+
+var blendModeNames = []string{
+	"None",
+	"Alpha",
+	"Additive",
+	"Premultiplied",
+}
+
+func (mode BlendMode) String() string {
+	if int(mode) < len(blendModeNames) {
+		return blendModeNames[mode]
+	}
+	return fmt.Sprintf("BlendMode(%d)", int(mode))
+}
+
+// The color blend attachment state for the mode.
+func (mode BlendMode) AttachmentState() vk.PipelineColorBlendAttachmentState {
+	// Create the result object.
+	state := vk.PipelineColorBlendAttachmentState{
+		ColorWriteMask: vk.ColorComponentFlags(vk.ColorComponentRBit | vk.ColorComponentGBit | vk.ColorComponentBBit | vk.ColorComponentABit),
+		BlendEnable:    vk.False,
+		ColorBlendOp:   vk.BlendOpAdd,
+		AlphaBlendOp:   vk.BlendOpAdd,
+	}
+
+	// Blend factors.
+	switch mode {
+	case BlendAlpha:
+		state.BlendEnable = vk.True
+		state.SrcColorBlendFactor = vk.BlendFactorSrcAlpha
+		state.DstColorBlendFactor = vk.BlendFactorOneMinusSrcAlpha
+		state.SrcAlphaBlendFactor = vk.BlendFactorOne
+		state.DstAlphaBlendFactor = vk.BlendFactorOneMinusSrcAlpha
+	case BlendAdditive:
+		state.BlendEnable = vk.True
+		state.SrcColorBlendFactor = vk.BlendFactorOne
+		state.DstColorBlendFactor = vk.BlendFactorOne
+		state.SrcAlphaBlendFactor = vk.BlendFactorOne
+		state.DstAlphaBlendFactor = vk.BlendFactorOne
+	case BlendPremultiplied:
+		state.BlendEnable = vk.True
+		state.SrcColorBlendFactor = vk.BlendFactorOne
+		state.DstColorBlendFactor = vk.BlendFactorOneMinusSrcAlpha
+		state.SrcAlphaBlendFactor = vk.BlendFactorOne
+		state.DstAlphaBlendFactor = vk.BlendFactorOneMinusSrcAlpha
+	}
+	return state
+}
+
+// GraphicsPipelineDesc describes the fixed function state of a graphics
+// pipeline. The With methods return a modified copy, so one description can
+// be the base of several pipelines.
+type GraphicsPipelineDesc struct {
+	// Name used in error messages.
+	Name string
+
+	// Shaders. Empty files use the application's shaders, empty entry
+	// points select the only entry point of the module.
+	VertexShaderFile   string
+	VertexEntryPoint   string
+	FragmentShaderFile string
+	FragmentEntryPoint string
+
+	// Vertex input.
+	VertexBindings   []vk.VertexInputBindingDescription
+	VertexAttributes []vk.VertexInputAttributeDescription
+
+	// Input assembly.
+	Topology         vk.PrimitiveTopology
+	PrimitiveRestart bool
+
+	// Rasterization.
+	PolygonMode vk.PolygonMode
+	CullMode    vk.CullModeFlags
+	FrontFace   vk.FrontFace
+	LineWidth   float32
+
+	// Color blending.
+	Blend BlendMode
+
+	// Depth and stencil. These need a depth buffer, see
+	// TriangleApplication.DepthFormats.
+	DepthTest      bool
+	DepthWrite     bool
+	DepthCompareOp vk.CompareOp
+	StencilTest    bool
+	StencilFront   vk.StencilOpState
+	StencilBack    vk.StencilOpState
+
+	// Multisampling. Sample shading is disabled when MinSampleShading is 0.
+	Samples          vk.SampleCountFlagBits
+	MinSampleShading float32
+
+	// Uniform buffer bindings that are bound with dynamic offsets, as
+	// UniformRing needs. The pipelines share one layout, so a binding
+	// listed by any description is dynamic in all of them.
+	DynamicUniformBuffers []SetBinding
+}
+
+// NewGraphicsPipelineDesc returns the state the triangle has always used: a
+// filled triangle list, back faces culled, clockwise front faces, no
+// blending and 1 sample.
+func NewGraphicsPipelineDesc() GraphicsPipelineDesc {
+	return GraphicsPipelineDesc{
+		Topology:       vk.PrimitiveTopologyTriangleList,
+		PolygonMode:    vk.PolygonModeFill,
+		CullMode:       vk.CullModeFlags(vk.CullModeBackBit),
+		FrontFace:      vk.FrontFaceClockwise,
+		LineWidth:      1.0,
+		Blend:          BlendNone,
+		DepthCompareOp: vk.CompareOpLess,
+		Samples:        vk.SampleCount1Bit,
+	}
+}
+
+// Fluent overrides.
+func (desc GraphicsPipelineDesc) WithName(name string) GraphicsPipelineDesc {
+	desc.Name = name
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithShaders(vertexFile, fragmentFile string) GraphicsPipelineDesc {
+	desc.VertexShaderFile = vertexFile
+	desc.FragmentShaderFile = fragmentFile
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithEntryPoints(vertex, fragment string) GraphicsPipelineDesc {
+	desc.VertexEntryPoint = vertex
+	desc.FragmentEntryPoint = fragment
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithVertexInput(bindings []vk.VertexInputBindingDescription,
+	attributes []vk.VertexInputAttributeDescription) GraphicsPipelineDesc {
+	desc.VertexBindings = append([]vk.VertexInputBindingDescription{}, bindings...)
+	desc.VertexAttributes = append([]vk.VertexInputAttributeDescription{}, attributes...)
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithTopology(topology vk.PrimitiveTopology) GraphicsPipelineDesc {
+	desc.Topology = topology
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithPrimitiveRestart(enable bool) GraphicsPipelineDesc {
+	desc.PrimitiveRestart = enable
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithPolygonMode(mode vk.PolygonMode) GraphicsPipelineDesc {
+	desc.PolygonMode = mode
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithCullMode(mode vk.CullModeFlagBits, frontFace vk.FrontFace) GraphicsPipelineDesc {
+	desc.CullMode = vk.CullModeFlags(mode)
+	desc.FrontFace = frontFace
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithLineWidth(width float32) GraphicsPipelineDesc {
+	desc.LineWidth = width
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithBlend(mode BlendMode) GraphicsPipelineDesc {
+	desc.Blend = mode
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithDepth(test, write bool, compareOp vk.CompareOp) GraphicsPipelineDesc {
+	desc.DepthTest = test
+	desc.DepthWrite = write
+	desc.DepthCompareOp = compareOp
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithStencil(front, back vk.StencilOpState) GraphicsPipelineDesc {
+	desc.StencilTest = true
+	desc.StencilFront = front
+	desc.StencilBack = back
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithMultisample(samples vk.SampleCountFlagBits, minSampleShading float32) GraphicsPipelineDesc {
+	desc.Samples = samples
+	desc.MinSampleShading = minSampleShading
+	return desc
+}
+
+func (desc GraphicsPipelineDesc) WithDynamicUniformBuffer(set, binding uint32) GraphicsPipelineDesc {
+	desc.DynamicUniformBuffers = append(append([]SetBinding{}, desc.DynamicUniformBuffers...), SetBinding{set, binding})
+	return desc
+}
+
+// CheckFeatures reports the first state of the description that needs a
+// device feature which isn't enabled.
+func (desc GraphicsPipelineDesc) CheckFeatures(features vk.PhysicalDeviceFeatures) error {
+	if desc.PolygonMode != vk.PolygonModeFill && features.FillModeNonSolid != vk.True {
+		mode := fmt.Sprintf("%d", desc.PolygonMode)
+		for name, v := range polygonModeNames {
+			if vk.PolygonMode(v) == desc.PolygonMode {
+				mode = name
+			}
+		}
+		return fmt.Errorf("polygon mode %s needs the fillModeNonSolid device feature", mode)
+	}
+	if desc.LineWidth != 1 && features.WideLines != vk.True {
+		return fmt.Errorf("line width %g needs the wideLines device feature", desc.LineWidth)
+	}
+	if desc.MinSampleShading > 0 && features.SampleRateShading != vk.True {
+		return fmt.Errorf("min sample shading %g needs the sampleRateShading device feature", desc.MinSampleShading)
+	}
+	return nil
+}
+
+// Convert a bool to a Vulkan bool.
+func vkBool(b bool) vk.Bool32 {
+	if b {
+		return vk.True
+	}
+	return vk.False
+}
+
+// CreateInfo builds the create info for the description. The viewport and
+// scissor are dynamic, so the pipeline doesn't depend on the swapchain
+// extent; they must be set when recording.
+func (desc GraphicsPipelineDesc) CreateInfo(stages []vk.PipelineShaderStageCreateInfo,
+	layout vk.PipelineLayout,
+	renderPass vk.RenderPass,
+	subpass uint32) vk.GraphicsPipelineCreateInfo {
+
+	// Multisampling.
+	multisampleState := &vk.PipelineMultisampleStateCreateInfo{
+		SType:                vk.StructureTypePipelineMultisampleStateCreateInfo,
+		SampleShadingEnable:  vkBool(desc.MinSampleShading > 0),
+		MinSampleShading:     desc.MinSampleShading,
+		RasterizationSamples: desc.Samples,
+	}
+
+	// Depth and stencil.
+	depthStencilState := &vk.PipelineDepthStencilStateCreateInfo{
+		SType:             vk.StructureTypePipelineDepthStencilStateCreateInfo,
+		DepthTestEnable:   vkBool(desc.DepthTest),
+		DepthWriteEnable:  vkBool(desc.DepthWrite),
+		DepthCompareOp:    desc.DepthCompareOp,
+		StencilTestEnable: vkBool(desc.StencilTest),
+		Front:             desc.StencilFront,
+		Back:              desc.StencilBack,
+		MaxDepthBounds:    1.0,
+	}
+
+	// Create the info object.
+	return vk.GraphicsPipelineCreateInfo{
+		SType:      vk.StructureTypeGraphicsPipelineCreateInfo,
+		StageCount: uint32(len(stages)),
+		PStages:    stages,
+		PVertexInputState: &vk.PipelineVertexInputStateCreateInfo{
+			SType:                           vk.StructureTypePipelineVertexInputStateCreateInfo,
+			VertexBindingDescriptionCount:   uint32(len(desc.VertexBindings)),
+			PVertexBindingDescriptions:      desc.VertexBindings,
+			VertexAttributeDescriptionCount: uint32(len(desc.VertexAttributes)),
+			PVertexAttributeDescriptions:    desc.VertexAttributes,
+		},
+		PInputAssemblyState: &vk.PipelineInputAssemblyStateCreateInfo{
+			SType:                  vk.StructureTypePipelineInputAssemblyStateCreateInfo,
+			Topology:               desc.Topology,
+			PrimitiveRestartEnable: vkBool(desc.PrimitiveRestart),
+		},
+		PViewportState: &vk.PipelineViewportStateCreateInfo{
+			SType:         vk.StructureTypePipelineViewportStateCreateInfo,
+			ViewportCount: 1,
+			ScissorCount:  1,
+		},
+		PDynamicState: &vk.PipelineDynamicStateCreateInfo{
+			SType:             vk.StructureTypePipelineDynamicStateCreateInfo,
+			DynamicStateCount: 2,
+			PDynamicStates: []vk.DynamicState{
+				vk.DynamicStateViewport,
+				vk.DynamicStateScissor,
+			},
+		},
+		PRasterizationState: &vk.PipelineRasterizationStateCreateInfo{
+			SType:                   vk.StructureTypePipelineRasterizationStateCreateInfo,
+			DepthClampEnable:        vk.False,
+			RasterizerDiscardEnable: vk.False,
+			PolygonMode:             desc.PolygonMode,
+			LineWidth:               desc.LineWidth,
+			CullMode:                desc.CullMode,
+			FrontFace:               desc.FrontFace,
+			DepthBiasEnable:         vk.False,
+		},
+		PMultisampleState:  multisampleState,
+		PDepthStencilState: depthStencilState,
+		PColorBlendState: &vk.PipelineColorBlendStateCreateInfo{
+			SType:           vk.StructureTypePipelineColorBlendStateCreateInfo,
+			LogicOpEnable:   vk.False,
+			LogicOp:         vk.LogicOpCopy,
+			AttachmentCount: 1,
+			PAttachments: []vk.PipelineColorBlendAttachmentState{
+				desc.Blend.AttachmentState(),
+			},
+		},
+		Layout:     layout,
+		RenderPass: renderPass,
+		Subpass:    subpass,
+	}
+}
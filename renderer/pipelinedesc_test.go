@@ -0,0 +1,84 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestGraphicsPipelineDescCheckFeatures(t *testing.T) {
+	all := vk.PhysicalDeviceFeatures{
+		FillModeNonSolid:  vk.True,
+		WideLines:         vk.True,
+		SampleRateShading: vk.True,
+	}
+	base := NewGraphicsPipelineDesc()
+	tests := []struct {
+		name     string
+		desc     GraphicsPipelineDesc
+		features vk.PhysicalDeviceFeatures
+		want     string // The error, or empty for none.
+	}{
+		{"default", base, vk.PhysicalDeviceFeatures{}, ""},
+		{"line mode", base.WithPolygonMode(vk.PolygonModeLine), vk.PhysicalDeviceFeatures{},
+			"polygon mode LINE needs the fillModeNonSolid device feature"},
+		{"point mode", base.WithPolygonMode(vk.PolygonModePoint), vk.PhysicalDeviceFeatures{},
+			"polygon mode POINT needs the fillModeNonSolid device feature"},
+		{"line mode enabled", base.WithPolygonMode(vk.PolygonModeLine), all, ""},
+		{"wide lines", base.WithLineWidth(2.5), vk.PhysicalDeviceFeatures{FillModeNonSolid: vk.True},
+			"line width 2.5 needs the wideLines device feature"},
+		{"wide lines enabled", base.WithLineWidth(2.5), all, ""},
+		{"sample shading", base.WithMultisample(vk.SampleCount4Bit, 0.5), vk.PhysicalDeviceFeatures{},
+			"min sample shading 0.5 needs the sampleRateShading device feature"},
+		{"sample shading enabled", base.WithMultisample(vk.SampleCount4Bit, 0.5), all, ""},
+		{"multisample without sample shading", base.WithMultisample(vk.SampleCount4Bit, 0), vk.PhysicalDeviceFeatures{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.desc.CheckFeatures(test.features)
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.want != "" && (err == nil || err.Error() != test.want):
+				t.Errorf("expected %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestGraphicsPipelineDescWithDynamicUniformBuffer(t *testing.T) {
+	// Descriptions built from the same base don't share the list.
+	base := NewGraphicsPipelineDesc().WithDynamicUniformBuffer(0, 0)
+	a := base.WithDynamicUniformBuffer(0, 1)
+	b := base.WithDynamicUniformBuffer(1, 0)
+
+	for _, test := range []struct {
+		desc GraphicsPipelineDesc
+		want []SetBinding
+	}{
+		{base, []SetBinding{{0, 0}}},
+		{a, []SetBinding{{0, 0}, {0, 1}}},
+		{b, []SetBinding{{0, 0}, {1, 0}}},
+	} {
+		if !reflect.DeepEqual(test.desc.DynamicUniformBuffers, test.want) {
+			t.Errorf("DynamicUniformBuffers = %v, expected %v", test.desc.DynamicUniformBuffers, test.want)
+		}
+	}
+}
+
+func TestGraphicsPipelineDescWithVertexInput(t *testing.T) {
+	// The description keeps its own copy of the slices.
+	bindings := []vk.VertexInputBindingDescription{{Binding: 0, Stride: 8}}
+	attributes := []vk.VertexInputAttributeDescription{{Location: 0, Format: vk.FormatR32g32Sfloat}}
+	desc := NewGraphicsPipelineDesc().WithVertexInput(bindings, attributes)
+	bindings[0].Stride = 16
+	attributes[0].Location = 1
+
+	want := NewGraphicsPipelineDesc().WithVertexInput(
+		[]vk.VertexInputBindingDescription{{Binding: 0, Stride: 8}},
+		[]vk.VertexInputAttributeDescription{{Location: 0, Format: vk.FormatR32g32Sfloat}})
+	if !reflect.DeepEqual(desc, want) {
+		t.Errorf("changing the caller's slices changed the description: %+v %+v", desc.VertexBindings, desc.VertexAttributes)
+	}
+}
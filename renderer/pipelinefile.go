@@ -0,0 +1,492 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Names accepted for enums in pipeline files. They are the Vulkan names
+// without the type prefix.
+var (
+	topologyNames = map[string]int64{
+		"POINT_LIST":     int64(vk.PrimitiveTopologyPointList),
+		"LINE_LIST":      int64(vk.PrimitiveTopologyLineList),
+		"LINE_STRIP":     int64(vk.PrimitiveTopologyLineStrip),
+		"TRIANGLE_LIST":  int64(vk.PrimitiveTopologyTriangleList),
+		"TRIANGLE_STRIP": int64(vk.PrimitiveTopologyTriangleStrip),
+		"TRIANGLE_FAN":   int64(vk.PrimitiveTopologyTriangleFan),
+	}
+	polygonModeNames = map[string]int64{
+		"FILL":  int64(vk.PolygonModeFill),
+		"LINE":  int64(vk.PolygonModeLine),
+		"POINT": int64(vk.PolygonModePoint),
+	}
+	cullModeNames = map[string]int64{
+		"NONE":           int64(vk.CullModeNone),
+		"FRONT":          int64(vk.CullModeFrontBit),
+		"BACK":           int64(vk.CullModeBackBit),
+		"FRONT_AND_BACK": int64(vk.CullModeFrontAndBack),
+	}
+	frontFaceNames = map[string]int64{
+		"COUNTER_CLOCKWISE": int64(vk.FrontFaceCounterClockwise),
+		"CLOCKWISE":         int64(vk.FrontFaceClockwise),
+	}
+	blendModeFileNames = map[string]int64{
+		"NONE":          int64(BlendNone),
+		"ALPHA":         int64(BlendAlpha),
+		"ADDITIVE":      int64(BlendAdditive),
+		"PREMULTIPLIED": int64(BlendPremultiplied),
+	}
+	compareOpNames = map[string]int64{
+		"NEVER":            int64(vk.CompareOpNever),
+		"LESS":             int64(vk.CompareOpLess),
+		"EQUAL":            int64(vk.CompareOpEqual),
+		"LESS_OR_EQUAL":    int64(vk.CompareOpLessOrEqual),
+		"GREATER":          int64(vk.CompareOpGreater),
+		"NOT_EQUAL":        int64(vk.CompareOpNotEqual),
+		"GREATER_OR_EQUAL": int64(vk.CompareOpGreaterOrEqual),
+		"ALWAYS":           int64(vk.CompareOpAlways),
+	}
+	inputRateNames = map[string]int64{
+		"VERTEX":   int64(vk.VertexInputRateVertex),
+		"INSTANCE": int64(vk.VertexInputRateInstance),
+	}
+	sampleCountNames = map[string]int64{
+		"1":  int64(vk.SampleCount1Bit),
+		"2":  int64(vk.SampleCount2Bit),
+		"4":  int64(vk.SampleCount4Bit),
+		"8":  int64(vk.SampleCount8Bit),
+		"16": int64(vk.SampleCount16Bit),
+		"32": int64(vk.SampleCount32Bit),
+		"64": int64(vk.SampleCount64Bit),
+	}
+	vertexFormatNames = map[string]int64{
+		"R8_UNORM":            int64(vk.FormatR8Unorm),
+		"R8_SNORM":            int64(vk.FormatR8Snorm),
+		"R8_UINT":             int64(vk.FormatR8Uint),
+		"R8_SINT":             int64(vk.FormatR8Sint),
+		"R8G8_UNORM":          int64(vk.FormatR8g8Unorm),
+		"R8G8_SNORM":          int64(vk.FormatR8g8Snorm),
+		"R8G8_UINT":           int64(vk.FormatR8g8Uint),
+		"R8G8_SINT":           int64(vk.FormatR8g8Sint),
+		"R8G8B8_UNORM":        int64(vk.FormatR8g8b8Unorm),
+		"R8G8B8_SNORM":        int64(vk.FormatR8g8b8Snorm),
+		"R8G8B8_UINT":         int64(vk.FormatR8g8b8Uint),
+		"R8G8B8_SINT":         int64(vk.FormatR8g8b8Sint),
+		"R8G8B8A8_UNORM":      int64(vk.FormatR8g8b8a8Unorm),
+		"R8G8B8A8_SNORM":      int64(vk.FormatR8g8b8a8Snorm),
+		"R8G8B8A8_UINT":       int64(vk.FormatR8g8b8a8Uint),
+		"R8G8B8A8_SINT":       int64(vk.FormatR8g8b8a8Sint),
+		"R16_UNORM":           int64(vk.FormatR16Unorm),
+		"R16_SNORM":           int64(vk.FormatR16Snorm),
+		"R16_UINT":            int64(vk.FormatR16Uint),
+		"R16_SINT":            int64(vk.FormatR16Sint),
+		"R16_SFLOAT":          int64(vk.FormatR16Sfloat),
+		"R16G16_UNORM":        int64(vk.FormatR16g16Unorm),
+		"R16G16_SNORM":        int64(vk.FormatR16g16Snorm),
+		"R16G16_UINT":         int64(vk.FormatR16g16Uint),
+		"R16G16_SINT":         int64(vk.FormatR16g16Sint),
+		"R16G16_SFLOAT":       int64(vk.FormatR16g16Sfloat),
+		"R16G16B16_UNORM":     int64(vk.FormatR16g16b16Unorm),
+		"R16G16B16_SNORM":     int64(vk.FormatR16g16b16Snorm),
+		"R16G16B16_UINT":      int64(vk.FormatR16g16b16Uint),
+		"R16G16B16_SINT":      int64(vk.FormatR16g16b16Sint),
+		"R16G16B16A16_UNORM":  int64(vk.FormatR16g16b16a16Unorm),
+		"R16G16B16A16_SNORM":  int64(vk.FormatR16g16b16a16Snorm),
+		"R16G16B16A16_UINT":   int64(vk.FormatR16g16b16a16Uint),
+		"R16G16B16A16_SINT":   int64(vk.FormatR16g16b16a16Sint),
+		"R16G16B16A16_SFLOAT": int64(vk.FormatR16g16b16a16Sfloat),
+		"R32_UINT":            int64(vk.FormatR32Uint),
+		"R32_SINT":            int64(vk.FormatR32Sint),
+		"R32_SFLOAT":          int64(vk.FormatR32Sfloat),
+		"R32G32_UINT":         int64(vk.FormatR32g32Uint),
+		"R32G32_SINT":         int64(vk.FormatR32g32Sint),
+		"R32G32_SFLOAT":       int64(vk.FormatR32g32Sfloat),
+		"R32G32B32_UINT":      int64(vk.FormatR32g32b32Uint),
+		"R32G32B32_SINT":      int64(vk.FormatR32g32b32Sint),
+		"R32G32B32_SFLOAT":    int64(vk.FormatR32g32b32Sfloat),
+		"R32G32B32A32_UINT":   int64(vk.FormatR32g32b32a32Uint),
+		"R32G32B32A32_SINT":   int64(vk.FormatR32g32b32a32Sint),
+		"R32G32B32A32_SFLOAT": int64(vk.FormatR32g32b32a32Sfloat),
+	}
+)
+
+// PipelineFileError lists every problem found in a pipeline file. Each
+// problem starts with the path of the field it is about.
+type PipelineFileError struct {
+	File     string
+	Problems []string
+}
+
+func (err *PipelineFileError) Error() string {
+	return fmt.Sprintf("%s: %d problem(s):\n\t%s", err.File, len(err.Problems), strings.Join(err.Problems, "\n\t"))
+}
+
+// A JSON or YAML object being read, tracking which fields were asked for so
+// unknown fields can be reported.
+type fileObject struct {
+	path     string
+	fields   map[string]interface{}
+	asked    map[string]bool
+	problems *[]string
+}
+
+func (obj *fileObject) problem(path, format string, args ...interface{}) {
+	*obj.problems = append(*obj.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Look up a field, returning false if it is absent.
+func (obj *fileObject) field(name string) (interface{}, string, bool) {
+	obj.asked[name] = true
+	v, ok := obj.fields[name]
+	return v, obj.path + "." + name, ok
+}
+
+// Report the fields that were never asked for.
+func (obj *fileObject) finish() {
+	valid := make([]string, 0, len(obj.asked))
+	for name := range obj.asked {
+		valid = append(valid, name)
+	}
+	sort.Strings(valid)
+
+	unknown := make([]string, 0)
+	for name := range obj.fields {
+		if !obj.asked[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		obj.problem(obj.path+"."+name, "unknown field, valid fields are %s", strings.Join(valid, ", "))
+	}
+}
+
+func (obj *fileObject) newObject(path string, v interface{}) *fileObject {
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		obj.problem(path, "expected an object, found %s", jsonKind(v))
+		return nil
+	}
+	return &fileObject{
+		path:     path,
+		fields:   fields,
+		asked:    make(map[string]bool),
+		problems: obj.problems,
+	}
+}
+
+// Read a nested object, calling read if it is present and valid.
+func (obj *fileObject) object(name string, read func(*fileObject)) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		return
+	}
+	if child := obj.newObject(path, v); child != nil {
+		read(child)
+		child.finish()
+	}
+}
+
+// Read an array of objects, calling read for each valid element.
+func (obj *fileObject) objects(name string, required bool, read func(int, *fileObject)) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		if required {
+			obj.problem(path, "required field is missing")
+		}
+		return
+	}
+	elems, ok := v.([]interface{})
+	if !ok {
+		obj.problem(path, "expected an array, found %s", jsonKind(v))
+		return
+	}
+	if required && len(elems) == 0 {
+		obj.problem(path, "must not be empty")
+	}
+	for k, elem := range elems {
+		if child := obj.newObject(fmt.Sprintf("%s[%d]", path, k), elem); child != nil {
+			read(k, child)
+			child.finish()
+		}
+	}
+}
+
+func (obj *fileObject) string(name string, dst *string) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		return
+	}
+	s, ok := v.(string)
+	if !ok {
+		obj.problem(path, "expected a string, found %s", jsonKind(v))
+		return
+	}
+	*dst = s
+}
+
+func (obj *fileObject) bool(name string, dst *bool) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		return
+	}
+	b, ok := v.(bool)
+	if !ok {
+		obj.problem(path, "expected true or false, found %s", jsonKind(v))
+		return
+	}
+	*dst = b
+}
+
+func (obj *fileObject) uint32(name string, required bool, dst *uint32) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		if required {
+			obj.problem(path, "required field is missing")
+		}
+		return
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		obj.problem(path, "expected a number, found %s", jsonKind(v))
+		return
+	}
+	i, err := n.Int64()
+	if err != nil || i < 0 || i > math.MaxUint32 {
+		obj.problem(path, "expected an integer from 0 to %d, found %s", uint32(math.MaxUint32), n)
+		return
+	}
+	*dst = uint32(i)
+}
+
+func (obj *fileObject) float32(name string, dst *float32) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		return
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		obj.problem(path, "expected a number, found %s", jsonKind(v))
+		return
+	}
+	f, err := n.Float64()
+	if err != nil {
+		obj.problem(path, "expected a number, found %s", n)
+		return
+	}
+	*dst = float32(f)
+}
+
+// Read an enum by name. Numbers are accepted for enums with numeric names.
+func (obj *fileObject) enum(name string, required bool, names map[string]int64, set func(int64)) {
+	v, path, ok := obj.field(name)
+	if !ok {
+		if required {
+			obj.problem(path, "required field is missing")
+		}
+		return
+	}
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	default:
+		obj.problem(path, "expected a string, found %s", jsonKind(v))
+		return
+	}
+	value, ok := names[s]
+	if !ok {
+		valid := make([]string, 0, len(names))
+		for name := range names {
+			valid = append(valid, name)
+		}
+		sort.Strings(valid)
+		obj.problem(path, "unknown value %q, valid values are %s", s, strings.Join(valid, ", "))
+		return
+	}
+	set(value)
+}
+
+func jsonKind(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "the number " + v.String()
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ParsePipelineDescs reads pipeline descriptions from JSON, or YAML when fn
+// ends in .yaml or .yml. Fields that are left out keep the values from
+// NewGraphicsPipelineDesc. Relative shader paths are resolved against dir.
+// fn is otherwise only used in errors.
+func ParsePipelineDescs(fn, dir string, data []byte) ([]GraphicsPipelineDesc, error) {
+	// Decode the document.
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".yaml", ".yml":
+		var err error
+		if doc, err = decodeYAML(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	// Create the result objects.
+	problems := make([]string, 0)
+	root := &fileObject{problems: &problems}
+	root = root.newObject("$", doc)
+	if root == nil {
+		return nil, &PipelineFileError{File: fn, Problems: problems}
+	}
+	descs := make([]GraphicsPipelineDesc, 0)
+
+	// Function for reading a shader stage.
+	readStage := func(stage *fileObject, file, entryPoint *string) {
+		stage.string("shader", file)
+		stage.string("entryPoint", entryPoint)
+		if *file == "" {
+			stage.problem(stage.path+".shader", "required field is missing")
+		} else if !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+
+	// Read each pipeline.
+	root.objects("pipelines", true, func(k int, obj *fileObject) {
+		desc := NewGraphicsPipelineDesc().WithName(fmt.Sprintf("pipelines[%d]", k))
+		obj.string("name", &desc.Name)
+
+		// Shaders.
+		obj.object("vertex", func(stage *fileObject) {
+			readStage(stage, &desc.VertexShaderFile, &desc.VertexEntryPoint)
+		})
+		obj.object("fragment", func(stage *fileObject) {
+			readStage(stage, &desc.FragmentShaderFile, &desc.FragmentEntryPoint)
+		})
+
+		// Vertex input.
+		obj.object("vertexInput", func(input *fileObject) {
+			input.objects("bindings", false, func(h int, binding *fileObject) {
+				b := vk.VertexInputBindingDescription{InputRate: vk.VertexInputRateVertex}
+				binding.uint32("binding", true, &b.Binding)
+				binding.uint32("stride", true, &b.Stride)
+				binding.enum("inputRate", false, inputRateNames, func(v int64) { b.InputRate = vk.VertexInputRate(v) })
+				for _, other := range desc.VertexBindings {
+					if other.Binding == b.Binding {
+						binding.problem(binding.path+".binding", "binding %d is declared more than once", b.Binding)
+					}
+				}
+				desc.VertexBindings = append(desc.VertexBindings, b)
+			})
+			input.objects("attributes", false, func(h int, attribute *fileObject) {
+				a := vk.VertexInputAttributeDescription{}
+				attribute.uint32("location", true, &a.Location)
+				attribute.uint32("binding", false, &a.Binding)
+				attribute.enum("format", true, vertexFormatNames, func(v int64) { a.Format = vk.Format(v) })
+				attribute.uint32("offset", false, &a.Offset)
+				for _, other := range desc.VertexAttributes {
+					if other.Location == a.Location {
+						attribute.problem(attribute.path+".location", "location %d is used more than once", a.Location)
+					}
+				}
+				declared := false
+				for _, b := range desc.VertexBindings {
+					declared = declared || b.Binding == a.Binding
+				}
+				if !declared {
+					attribute.problem(attribute.path+".binding", "binding %d is not declared in vertexInput.bindings", a.Binding)
+				}
+				desc.VertexAttributes = append(desc.VertexAttributes, a)
+			})
+		})
+
+		// Input assembly.
+		obj.object("inputAssembly", func(ia *fileObject) {
+			ia.enum("topology", false, topologyNames, func(v int64) { desc.Topology = vk.PrimitiveTopology(v) })
+			ia.bool("primitiveRestart", &desc.PrimitiveRestart)
+		})
+
+		// Rasterization.
+		obj.object("rasterization", func(r *fileObject) {
+			r.enum("polygonMode", false, polygonModeNames, func(v int64) { desc.PolygonMode = vk.PolygonMode(v) })
+			r.enum("cullMode", false, cullModeNames, func(v int64) { desc.CullMode = vk.CullModeFlags(v) })
+			r.enum("frontFace", false, frontFaceNames, func(v int64) { desc.FrontFace = vk.FrontFace(v) })
+			r.float32("lineWidth", &desc.LineWidth)
+			if desc.LineWidth <= 0 {
+				r.problem(r.path+".lineWidth", "must be greater than 0")
+			}
+		})
+
+		// Blending.
+		obj.enum("blend", false, blendModeFileNames, func(v int64) { desc.Blend = BlendMode(v) })
+
+		// Depth.
+		obj.object("depthStencil", func(ds *fileObject) {
+			ds.bool("depthTest", &desc.DepthTest)
+			ds.bool("depthWrite", &desc.DepthWrite)
+			ds.enum("depthCompareOp", false, compareOpNames, func(v int64) { desc.DepthCompareOp = vk.CompareOp(v) })
+		})
+
+		// Multisampling.
+		obj.object("multisample", func(ms *fileObject) {
+			ms.enum("samples", false, sampleCountNames, func(v int64) { desc.Samples = vk.SampleCountFlagBits(v) })
+			ms.float32("minSampleShading", &desc.MinSampleShading)
+			if desc.MinSampleShading < 0 || desc.MinSampleShading > 1 {
+				ms.problem(ms.path+".minSampleShading", "must be from 0 to 1")
+			}
+		})
+
+		// Descriptors.
+		obj.objects("dynamicUniformBuffers", false, func(h int, ub *fileObject) {
+			b := SetBinding{}
+			ub.uint32("set", false, &b.Set)
+			ub.uint32("binding", true, &b.Binding)
+			desc.DynamicUniformBuffers = append(desc.DynamicUniformBuffers, b)
+		})
+
+		descs = append(descs, desc)
+	})
+	root.finish()
+
+	// Return the descriptions.
+	if len(problems) > 0 {
+		return nil, &PipelineFileError{File: fn, Problems: problems}
+	}
+	return descs, nil
+}
+
+// LoadPipelineDescs reads pipeline descriptions from a JSON or YAML file.
+// Shader paths are relative to the file.
+func LoadPipelineDescs(fn string) ([]GraphicsPipelineDesc, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePipelineDescs(fn, filepath.Dir(fn), data)
+}
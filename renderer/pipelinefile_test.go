@@ -0,0 +1,240 @@
+package renderer
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// A pipeline file with one pipeline, with fields added after the shaders.
+func pipelineFile(fields string) string {
+	return `{"pipelines": [{
+		"vertex": {"shader": "vert.spv"},
+		"fragment": {"shader": "frag.spv", "entryPoint": "main"}` + fields + `}]}`
+}
+
+func TestParsePipelineDescs(t *testing.T) {
+	descs, err := ParsePipelineDescs("test.json", "shaders", []byte(pipelineFile(`,
+		"name": "lines",
+		"vertexInput": {
+			"bindings": [{"binding": 0, "stride": 12}],
+			"attributes": [{"location": 0, "format": "R8G8B8A8_UNORM", "offset": 4}]
+		},
+		"inputAssembly": {"topology": "LINE_STRIP"},
+		"rasterization": {"polygonMode": "LINE", "cullMode": "NONE", "lineWidth": 2.5},
+		"blend": "ALPHA",
+		"multisample": {"samples": 4},
+		"dynamicUniformBuffers": [{"binding": 2}, {"set": 1, "binding": 0}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 {
+		t.Fatalf("got %d descriptions, expected 1", len(descs))
+	}
+	desc := descs[0]
+
+	if desc.Name != "lines" ||
+		desc.VertexShaderFile != filepath.Join("shaders", "vert.spv") ||
+		desc.FragmentShaderFile != filepath.Join("shaders", "frag.spv") ||
+		desc.FragmentEntryPoint != "main" {
+		t.Errorf("name and shaders: %q %q %q %q", desc.Name, desc.VertexShaderFile, desc.FragmentShaderFile, desc.FragmentEntryPoint)
+	}
+	wantBindings := []vk.VertexInputBindingDescription{{Binding: 0, Stride: 12, InputRate: vk.VertexInputRateVertex}}
+	if !reflect.DeepEqual(desc.VertexBindings, wantBindings) {
+		t.Errorf("bindings %+v, expected %+v", desc.VertexBindings, wantBindings)
+	}
+	wantAttributes := []vk.VertexInputAttributeDescription{{Location: 0, Binding: 0, Format: vk.FormatR8g8b8a8Unorm, Offset: 4}}
+	if !reflect.DeepEqual(desc.VertexAttributes, wantAttributes) {
+		t.Errorf("attributes %+v, expected %+v", desc.VertexAttributes, wantAttributes)
+	}
+	if desc.Topology != vk.PrimitiveTopologyLineStrip ||
+		desc.PolygonMode != vk.PolygonModeLine ||
+		desc.CullMode != vk.CullModeFlags(vk.CullModeNone) ||
+		desc.LineWidth != 2.5 ||
+		desc.Blend != BlendAlpha ||
+		desc.Samples != vk.SampleCount4Bit {
+		t.Errorf("state: %+v", desc)
+	}
+	wantDynamic := []SetBinding{{Set: 0, Binding: 2}, {Set: 1, Binding: 0}}
+	if !reflect.DeepEqual(desc.DynamicUniformBuffers, wantDynamic) {
+		t.Errorf("dynamic uniform buffers %+v, expected %+v", desc.DynamicUniformBuffers, wantDynamic)
+	}
+}
+
+func TestParsePipelineDescsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		problems []string
+	}{
+		{
+			name: "unknown root field",
+			file: `{"pipelines": [{"vertex": {"shader": "vert.spv"}}], "pipeline": []}`,
+			problems: []string{
+				"$.pipeline: unknown field, valid fields are pipelines",
+			},
+		},
+		{
+			name: "unknown nested field",
+			file: pipelineFile(`, "rasterization": {"polygonMod": "LINE"}`),
+			problems: []string{
+				"$.pipelines[0].rasterization.polygonMod: unknown field, valid fields are cullMode, frontFace, lineWidth, polygonMode",
+			},
+		},
+		{
+			name: "unknown field in an array",
+			file: pipelineFile(`, "vertexInput": {
+				"bindings": [{"binding": 0, "stride": 8}],
+				"attributes": [{"location": 0, "format": "R32G32_SFLOAT"}, {"location": 1, "fmt": "R32_SFLOAT"}]
+			}`),
+			problems: []string{
+				"$.pipelines[0].vertexInput.attributes[1].format: required field is missing",
+				"$.pipelines[0].vertexInput.attributes[1].fmt: unknown field, valid fields are binding, format, location, offset",
+			},
+		},
+		{
+			name: "bad enum",
+			file: pipelineFile(`, "rasterization": {"polygonMode": "WIRE"}`),
+			problems: []string{
+				`$.pipelines[0].rasterization.polygonMode: unknown value "WIRE", valid values are FILL, LINE, POINT`,
+			},
+		},
+		{
+			name: "bad numeric enum",
+			file: pipelineFile(`, "multisample": {"samples": 3}`),
+			problems: []string{
+				`$.pipelines[0].multisample.samples: unknown value "3", valid values are 1, 16, 2, 32, 4, 64, 8`,
+			},
+		},
+		{
+			name: "enum of the wrong type",
+			file: pipelineFile(`, "blend": true`),
+			problems: []string{
+				"$.pipelines[0].blend: expected a string, found a boolean",
+			},
+		},
+		{
+			name: "bad numbers",
+			file: pipelineFile(`, "vertexInput": {"bindings": [{"binding": -1, "stride": "8"}]},
+				"rasterization": {"lineWidth": 0}`),
+			problems: []string{
+				"$.pipelines[0].vertexInput.bindings[0].binding: expected an integer from 0 to 4294967295, found -1",
+				`$.pipelines[0].vertexInput.bindings[0].stride: expected a number, found the string "8"`,
+				"$.pipelines[0].rasterization.lineWidth: must be greater than 0",
+			},
+		},
+		{
+			name: "missing fields",
+			file: `{"pipelines": [{"vertex": {"entryPoint": "main"}, "vertexInput": {"attributes": [{"location": 0, "binding": 1, "format": "R32_SFLOAT"}]}}]}`,
+			problems: []string{
+				"$.pipelines[0].vertex.shader: required field is missing",
+				"$.pipelines[0].vertexInput.attributes[0].binding: binding 1 is not declared in vertexInput.bindings",
+			},
+		},
+		{
+			name: "no pipelines",
+			file: `{"pipelines": {}}`,
+			problems: []string{
+				"$.pipelines: expected an array, found an object",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParsePipelineDescs("test.json", "", []byte(test.file))
+			var fileErr *PipelineFileError
+			if !errors.As(err, &fileErr) {
+				t.Fatalf("expected a PipelineFileError, got %v", err)
+			}
+			if fileErr.File != "test.json" || !reflect.DeepEqual(fileErr.Problems, test.problems) {
+				t.Errorf("%s problems:\n\t%s\nexpected:\n\t%s", fileErr.File,
+					strings.Join(fileErr.Problems, "\n\t"),
+					strings.Join(test.problems, "\n\t"))
+			}
+		})
+	}
+}
+
+func TestParsePipelineDescsSyntax(t *testing.T) {
+	_, err := ParsePipelineDescs("test.json", "", []byte(`{"pipelines": [`))
+	var fileErr *PipelineFileError
+	if err == nil || errors.As(err, &fileErr) || !strings.HasPrefix(err.Error(), "test.json: ") {
+		t.Errorf("expected a decoding error for test.json, got %v", err)
+	}
+}
+
+func TestParsePipelineDescsYAML(t *testing.T) {
+	jsonDescs, err := ParsePipelineDescs("test.json", "shaders", []byte(pipelineFile(`,
+		"name": "lines",
+		"vertexInput": {
+			"bindings": [{"binding": 0, "stride": 12}],
+			"attributes": [{"location": 0, "format": "R8G8B8A8_UNORM", "offset": 4}]
+		},
+		"rasterization": {"polygonMode": "LINE", "lineWidth": 2.5},
+		"multisample": {"samples": 4},
+		"dynamicUniformBuffers": [{"binding": 2}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlDescs, err := ParsePipelineDescs("test.yml", "shaders", []byte(`
+pipelines:
+- vertex:
+    shader: vert.spv
+  fragment: {shader: frag.spv, entryPoint: main}
+  name: lines # A comment.
+  vertexInput:
+    bindings: [{binding: 0, stride: 12}]
+    attributes:
+      - location: 0
+        format: R8G8B8A8_UNORM
+        offset: 4
+  rasterization: {polygonMode: LINE, lineWidth: 2.5}
+  multisample:
+    samples: 4
+  dynamicUniformBuffers:
+    - binding: 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(yamlDescs, jsonDescs) {
+		t.Errorf("YAML decoded to %+v\nJSON decoded to %+v", yamlDescs, jsonDescs)
+	}
+}
+
+func TestParsePipelineDescsYAMLErrors(t *testing.T) {
+	// Problems with the fields are reported the same way as for JSON.
+	_, err := ParsePipelineDescs("test.yaml", "", []byte("pipelines:\n  - vertex: {shader: vert.spv}\n    blend: yes\n    multisample: {samples: 1.5}\n"))
+	var fileErr *PipelineFileError
+	problems := []string{
+		`$.pipelines[0].blend: unknown value "yes", valid values are ADDITIVE, ALPHA, NONE, PREMULTIPLIED`,
+		`$.pipelines[0].multisample.samples: unknown value "1.5", valid values are 1, 16, 2, 32, 4, 64, 8`,
+	}
+	if !errors.As(err, &fileErr) || !reflect.DeepEqual(fileErr.Problems, problems) {
+		t.Errorf("expected problems %q, got %v", problems, err)
+	}
+
+	// Syntax errors have the file and line.
+	_, err = ParsePipelineDescs("test.yaml", "", []byte("pipelines:\n  - vertex: &v {}\n"))
+	if err == nil || errors.As(err, &fileErr) || !strings.HasPrefix(err.Error(), "test.yaml: yaml: line 2: ") {
+		t.Errorf("expected a decoding error for test.yaml, got %v", err)
+	}
+}
+
+func TestPipelineFileExamples(t *testing.T) {
+	jsonDescs, err := LoadPipelineDescs(filepath.Join("..", "shaders", "triangle.pipelines.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlDescs, err := LoadPipelineDescs(filepath.Join("..", "shaders", "triangle.pipelines.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(yamlDescs, jsonDescs) {
+		t.Errorf("triangle.pipelines.yaml decoded to %+v\ntriangle.pipelines.json decoded to %+v", yamlDescs, jsonDescs)
+	}
+}
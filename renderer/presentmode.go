@@ -0,0 +1,108 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// PresentPolicy says how frames are paced. Each policy is a chain of present
+// modes tried in order, ending in FIFO which every surface supports.
+type PresentPolicy int
+
+const (
+	PresentLowLatency  PresentPolicy = iota // Mailbox, then FIFO. The default.
+	PresentVSync                            // FIFO, never tears and saves power.
+	PresentUncapped                         // Immediate, then Mailbox, then FIFO. For benchmarks.
+	PresentFIFORelaxed                      // FIFO relaxed, then FIFO. Tears instead of stalling on late frames.
+)
+
+var presentPolicyNames = []string{
+	PresentLowLatency:  "low-latency",
+	PresentVSync:       "vsync",
+	PresentUncapped:    "uncapped",
+	PresentFIFORelaxed: "fifo-relaxed",
+}
+
+var presentModeNames = map[vk.PresentMode]string{
+	vk.PresentModeImmediate:   "IMMEDIATE",
+	vk.PresentModeMailbox:     "MAILBOX",
+	vk.PresentModeFifo:        "FIFO",
+	vk.PresentModeFifoRelaxed: "FIFO_RELAXED",
+}
+
+func (policy PresentPolicy) String() string {
+	if policy >= 0 && int(policy) < len(presentPolicyNames) {
+		return presentPolicyNames[policy]
+	}
+	return fmt.Sprintf("PresentPolicy(%d)", int(policy))
+}
+
+// Next policy, wrapping around. Used to cycle through them at runtime.
+func (policy PresentPolicy) Next() PresentPolicy {
+	return PresentPolicy((int(policy) + 1) % len(presentPolicyNames))
+}
+
+// Present modes to try, most preferred first.
+func (policy PresentPolicy) Chain() []vk.PresentMode {
+	switch policy {
+	case PresentVSync:
+		return []vk.PresentMode{vk.PresentModeFifo}
+	case PresentUncapped:
+		return []vk.PresentMode{vk.PresentModeImmediate, vk.PresentModeMailbox, vk.PresentModeFifo}
+	case PresentFIFORelaxed:
+		return []vk.PresentMode{vk.PresentModeFifoRelaxed, vk.PresentModeFifo}
+	default:
+		return []vk.PresentMode{vk.PresentModeMailbox, vk.PresentModeFifo}
+	}
+}
+
+// ParsePresentPolicy returns the policy with the name, as printed by String.
+func ParsePresentPolicy(name string) (PresentPolicy, error) {
+	for k, v := range presentPolicyNames {
+		if strings.EqualFold(v, name) {
+			return PresentPolicy(k), nil
+		}
+	}
+	return PresentLowLatency, fmt.Errorf("unknown present policy %q, expected one of %s",
+		name, strings.Join(presentPolicyNames, ", "))
+}
+
+// Name of a present mode for messages.
+func PresentModeName(mode vk.PresentMode) string {
+	if name, ok := presentModeNames[mode]; ok {
+		return name
+	}
+	return fmt.Sprintf("presentMode(%d)", mode)
+}
+
+// ChoosePresentMode picks the first mode in the policy's chain the surface
+// supports and explains the choice.
+func ChoosePresentMode(available []vk.PresentMode, policy PresentPolicy) (vk.PresentMode, string) {
+	chain := policy.Chain()
+	for k, mode := range chain {
+		for _, v := range available {
+			if v == mode {
+				if k == 0 {
+					return v, fmt.Sprintf("%s for the %s policy", PresentModeName(v), policy)
+				}
+
+				// Name every mode that was skipped.
+				skipped := make([]string, k)
+				for h, mode := range chain[:k] {
+					skipped[h] = PresentModeName(mode)
+				}
+				verb := "is"
+				if k > 1 {
+					verb = "are"
+				}
+				return v, fmt.Sprintf("%s for the %s policy, %s %s not supported",
+					PresentModeName(v), policy, strings.Join(skipped, " and "), verb)
+			}
+		}
+	}
+
+	// FIFO is required to be supported.
+	return vk.PresentModeFifo, fmt.Sprintf("FIFO for the %s policy, the surface reported none of %v", policy, available)
+}
@@ -0,0 +1,87 @@
+package renderer
+
+import (
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestChoosePresentMode(t *testing.T) {
+	all := []vk.PresentMode{vk.PresentModeImmediate, vk.PresentModeMailbox, vk.PresentModeFifo, vk.PresentModeFifoRelaxed}
+	fifo := []vk.PresentMode{vk.PresentModeFifo}
+	tests := []struct {
+		policy    PresentPolicy
+		available []vk.PresentMode
+		want      vk.PresentMode
+		reason    string
+	}{
+		{PresentLowLatency, all, vk.PresentModeMailbox, "MAILBOX for the low-latency policy"},
+		{PresentLowLatency, fifo, vk.PresentModeFifo, "FIFO for the low-latency policy, MAILBOX is not supported"},
+		{PresentVSync, all, vk.PresentModeFifo, "FIFO for the vsync policy"},
+		{PresentUncapped, all, vk.PresentModeImmediate, "IMMEDIATE for the uncapped policy"},
+		{PresentUncapped, []vk.PresentMode{vk.PresentModeFifo, vk.PresentModeMailbox}, vk.PresentModeMailbox,
+			"MAILBOX for the uncapped policy, IMMEDIATE is not supported"},
+		{PresentUncapped, fifo, vk.PresentModeFifo, "FIFO for the uncapped policy, IMMEDIATE and MAILBOX are not supported"},
+		{PresentFIFORelaxed, all, vk.PresentModeFifoRelaxed, "FIFO_RELAXED for the fifo-relaxed policy"},
+		{PresentFIFORelaxed, fifo, vk.PresentModeFifo, "FIFO for the fifo-relaxed policy, FIFO_RELAXED is not supported"},
+		{PresentUncapped, nil, vk.PresentModeFifo, "FIFO for the uncapped policy, the surface reported none of []"},
+	}
+	for _, test := range tests {
+		got, reason := ChoosePresentMode(test.available, test.policy)
+		if got != test.want || reason != test.reason {
+			t.Errorf("%s with %v: got %s, %q, expected %s, %q", test.policy, test.available,
+				PresentModeName(got), reason,
+				PresentModeName(test.want), test.reason)
+		}
+	}
+}
+
+func TestParsePresentPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		want PresentPolicy
+		err  string
+	}{
+		{name: "low-latency", want: PresentLowLatency},
+		{name: "vsync", want: PresentVSync},
+		{name: "Uncapped", want: PresentUncapped},
+		{name: "FIFO-RELAXED", want: PresentFIFORelaxed},
+		{name: "fast", err: `unknown present policy "fast", expected one of low-latency, vsync, uncapped, fifo-relaxed`},
+		{name: "", err: `unknown present policy "", expected one of low-latency, vsync, uncapped, fifo-relaxed`},
+	}
+	for _, test := range tests {
+		got, err := ParsePresentPolicy(test.name)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: expected the error %q, got %s, %v", test.name, test.err, got, err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %s, %v, expected %s", test.name, got, err, test.want)
+		}
+	}
+
+	// Every policy's name parses back to it.
+	for policy := PresentPolicy(0); int(policy) < len(presentPolicyNames); policy++ {
+		if got, err := ParsePresentPolicy(policy.String()); err != nil || got != policy {
+			t.Errorf("%s parsed as %s, %v", policy, got, err)
+		}
+	}
+}
+
+func TestPresentPolicyNext(t *testing.T) {
+	tests := []struct {
+		policy, want PresentPolicy
+	}{
+		{PresentLowLatency, PresentVSync},
+		{PresentVSync, PresentUncapped},
+		{PresentUncapped, PresentFIFORelaxed},
+		{PresentFIFORelaxed, PresentLowLatency},
+	}
+	for _, test := range tests {
+		if got := test.policy.Next(); got != test.want {
+			t.Errorf("%s.Next() = %s, expected %s", test.policy, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,847 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// SPIR-V opcodes used by reflection.
+const (
+	opName             = 5
+	opMemberName       = 6
+	opEntryPoint       = 15
+	opTypeVoid         = 19
+	opTypeBool         = 20
+	opTypeInt          = 21
+	opTypeFloat        = 22
+	opTypeVector       = 23
+	opTypeMatrix       = 24
+	opTypeImage        = 25
+	opTypeSampler      = 26
+	opTypeSampledImage = 27
+	opTypeArray        = 28
+	opTypeRuntimeArray = 29
+	opTypeStruct       = 30
+	opTypePointer      = 32
+	opTypeFunction     = 33
+	opConstant         = 43
+	opSpecConstant     = 50
+	opFunction         = 54
+	opFunctionEnd      = 56
+	opVariable         = 59
+	opDecorate         = 71
+	opMemberDecorate   = 72
+)
+
+// SPIR-V decorations used by reflection.
+const (
+	decorationBlock         = 2
+	decorationBufferBlock   = 3
+	decorationRowMajor      = 4
+	decorationColMajor      = 5
+	decorationArrayStride   = 6
+	decorationMatrixStride  = 7
+	decorationBuiltIn       = 11
+	decorationFlat          = 14
+	decorationNonWritable   = 24
+	decorationLocation      = 30
+	decorationComponent     = 31
+	decorationBinding       = 33
+	decorationDescriptorSet = 34
+	decorationOffset        = 35
+)
+
+// Execution Model
+type ExecutionModel uint32
+
+const (
+	ExecutionModelVertex ExecutionModel = iota
+	ExecutionModelTessellationControl
+	ExecutionModelTessellationEvaluation
+	ExecutionModelGeometry
+	ExecutionModelFragment
+	ExecutionModelGLCompute
+	ExecutionModelKernel
+)
+
+var executionModelNames = []string{
+	"Vertex",
+	"TessellationControl",
+	"TessellationEvaluation",
+	"Geometry",
+	"Fragment",
+	"GLCompute",
+	"Kernel",
+}
+
+func (model ExecutionModel) String() string {
+	if int(model) < len(executionModelNames) {
+		return executionModelNames[model]
+	}
+	return fmt.Sprintf("ExecutionModel(%d)", uint32(model))
+}
+
+// The Vulkan shader stage for the execution model, or 0 if there isn't one.
+func (model ExecutionModel) ShaderStage() vk.ShaderStageFlagBits {
+	switch model {
+	case ExecutionModelVertex:
+		return vk.ShaderStageVertexBit
+	case ExecutionModelTessellationControl:
+		return vk.ShaderStageTessellationControlBit
+	case ExecutionModelTessellationEvaluation:
+		return vk.ShaderStageTessellationEvaluationBit
+	case ExecutionModelGeometry:
+		return vk.ShaderStageGeometryBit
+	case ExecutionModelFragment:
+		return vk.ShaderStageFragmentBit
+	case ExecutionModelGLCompute:
+		return vk.ShaderStageComputeBit
+	}
+	return 0
+}
+
+// Storage Class
+type StorageClass uint32
+
+const (
+	StorageClassUniformConstant StorageClass = 0
+	StorageClassInput           StorageClass = 1
+	StorageClassUniform         StorageClass = 2
+	StorageClassOutput          StorageClass = 3
+	StorageClassPrivate         StorageClass = 6
+	StorageClassFunction        StorageClass = 7
+	StorageClassPushConstant    StorageClass = 9
+	StorageClassStorageBuffer   StorageClass = 12
+)
+
+// Decorations applied to an ID or a struct member.
+type Decorations struct {
+	Location      OptionUint32
+	Component     OptionUint32
+	BuiltIn       OptionUint32
+	DescriptorSet OptionUint32
+	Binding       OptionUint32
+	Offset        OptionUint32
+	ArrayStride   OptionUint32
+	MatrixStride  OptionUint32
+	Block         bool
+	BufferBlock   bool
+	RowMajor      bool
+	Flat          bool
+	NonWritable   bool
+}
+
+func (decs *Decorations) apply(decoration uint32, operands []uint32) {
+	// Decorations with a literal operand.
+	literal := func(option *OptionUint32) {
+		if len(operands) > 0 {
+			option.Set(operands[0])
+		}
+	}
+
+	switch decoration {
+	case decorationBlock:
+		decs.Block = true
+	case decorationBufferBlock:
+		decs.BufferBlock = true
+	case decorationRowMajor:
+		decs.RowMajor = true
+	case decorationColMajor:
+		decs.RowMajor = false
+	case decorationFlat:
+		decs.Flat = true
+	case decorationNonWritable:
+		decs.NonWritable = true
+	case decorationArrayStride:
+		literal(&decs.ArrayStride)
+	case decorationMatrixStride:
+		literal(&decs.MatrixStride)
+	case decorationBuiltIn:
+		literal(&decs.BuiltIn)
+	case decorationLocation:
+		literal(&decs.Location)
+	case decorationComponent:
+		literal(&decs.Component)
+	case decorationBinding:
+		literal(&decs.Binding)
+	case decorationDescriptorSet:
+		literal(&decs.DescriptorSet)
+	case decorationOffset:
+		literal(&decs.Offset)
+	}
+}
+
+// Type Kind
+type TypeKind int
+
+const (
+	TypeUnknown TypeKind = iota
+	TypeVoid
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeVector
+	TypeMatrix
+	TypeImage
+	TypeSampler
+	TypeSampledImage
+	TypeArray
+	TypeRuntimeArray
+	TypeStruct
+	TypePointer
+	TypeFunction
+)
+
+// A reflected type.
+type Type struct {
+	ID           uint32
+	Kind         TypeKind
+	Name         string
+	Width        uint32 // Bits, for ints and floats.
+	Signed       bool   // For ints.
+	Count        uint32 // Components, columns or array length.
+	Elem         *Type  // Component, column, array element or pointee type.
+	StorageClass StorageClass
+	Decorations  Decorations
+
+	// Struct members.
+	Members           []*Type
+	MemberNames       []string
+	MemberDecorations []Decorations
+
+	// Image dimensionality and whether it is sampled (1), storage (2) or
+	// unknown (0).
+	Dim     uint32
+	Sampled uint32
+}
+
+// Reports if two types have the same shape, ignoring IDs, names and
+// decorations.
+func (t *Type) Equal(other *Type) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	if t.Kind != other.Kind ||
+		t.Width != other.Width ||
+		t.Signed != other.Signed ||
+		t.Count != other.Count ||
+		len(t.Members) != len(other.Members) ||
+		!t.Elem.Equal(other.Elem) {
+		return false
+	}
+	for k := range t.Members {
+		if !t.Members[k].Equal(other.Members[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// GLSL-like spelling of the type for messages.
+func (t *Type) String() string {
+	if t == nil {
+		return "<nil>"
+	}
+	switch t.Kind {
+	case TypeVoid:
+		return "void"
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		if t.Signed {
+			return fmt.Sprintf("int%d", t.Width)
+		}
+		return fmt.Sprintf("uint%d", t.Width)
+	case TypeFloat:
+		return fmt.Sprintf("float%d", t.Width)
+	case TypeVector:
+		prefix := ""
+		switch {
+		case t.Elem.Kind == TypeFloat && t.Elem.Width == 64:
+			prefix = "d"
+		case t.Elem.Kind == TypeInt && t.Elem.Signed:
+			prefix = "i"
+		case t.Elem.Kind == TypeInt:
+			prefix = "u"
+		case t.Elem.Kind == TypeBool:
+			prefix = "b"
+		}
+		return fmt.Sprintf("%svec%d", prefix, t.Count)
+	case TypeMatrix:
+		return fmt.Sprintf("mat%dx%d", t.Count, t.Elem.Count)
+	case TypeArray:
+		return fmt.Sprintf("%s[%d]", t.Elem, t.Count)
+	case TypeRuntimeArray:
+		return fmt.Sprintf("%s[]", t.Elem)
+	case TypeStruct:
+		members := make([]string, len(t.Members))
+		for k, m := range t.Members {
+			members[k] = fmt.Sprintf("%s %s", m, t.MemberNames[k])
+		}
+		return fmt.Sprintf("struct %s{%s}", t.Name, strings.Join(members, "; "))
+	case TypePointer:
+		return fmt.Sprintf("%s*", t.Elem)
+	case TypeImage:
+		return "image"
+	case TypeSampler:
+		return "sampler"
+	case TypeSampledImage:
+		return "sampledImage"
+	case TypeFunction:
+		return "function"
+	}
+	return fmt.Sprintf("type(%d)", t.ID)
+}
+
+// A reflected global variable.
+type Variable struct {
+	ID           uint32
+	Name         string
+	Type         *Type // The pointee type.
+	StorageClass StorageClass
+	Decorations  Decorations
+}
+
+// Reports if the variable is a built-in, either directly or as a block of
+// built-in members such as gl_PerVertex.
+func (v *Variable) IsBuiltIn() bool {
+	if v.Decorations.BuiltIn.IsSet() {
+		return true
+	}
+	t := v.Type
+	for t != nil && (t.Kind == TypeArray || t.Kind == TypeRuntimeArray) {
+		t = t.Elem
+	}
+	if t != nil && t.Kind == TypeStruct {
+		for _, decs := range t.MemberDecorations {
+			if decs.BuiltIn.IsSet() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A reflected entry point.
+type EntryPoint struct {
+	ID      uint32
+	Name    string
+	Model   ExecutionModel
+	Inputs  []*Variable
+	Outputs []*Variable
+	// All the variables listed on the entry point.
+	Interface []*Variable
+	// IDs used by the entry point's function and the functions it calls.
+	// Literal operands are included too, so an ID may be listed that
+	// isn't really used, but none that is used are missing.
+	Uses map[uint32]bool
+}
+
+// Reflection of a SPIR-V module.
+type Reflection struct {
+	EntryPoints []EntryPoint
+	Types       map[uint32]*Type
+	Variables   map[uint32]*Variable
+	Constants   map[uint32]uint32 // 32-bit scalar constants by ID.
+}
+
+// Reflect decodes the entry points, types, variables and decorations of a
+// module.
+func Reflect(module *SPIRV) (*Reflection, error) {
+	refl := &Reflection{
+		Types:     make(map[uint32]*Type),
+		Variables: make(map[uint32]*Variable),
+		Constants: make(map[uint32]uint32),
+	}
+
+	// Debug names and decorations come before the types and variables they
+	// apply to, entry point interfaces come before the variables they list.
+	names := make(map[uint32]string)
+	memberNames := make(map[uint32]map[uint32]string)
+	decorations := make(map[uint32]*Decorations)
+	memberDecorations := make(map[uint32]map[uint32]*Decorations)
+	interfaces := make([][]uint32, 0)
+
+	// The operands of the instructions in each function body.
+	function := uint32(0)
+	functionOperands := make(map[uint32][]uint32)
+
+	// Look up a type, tolerating forward references.
+	typeOf := func(id uint32) *Type {
+		if t, ok := refl.Types[id]; ok {
+			return t
+		}
+		t := &Type{ID: id}
+		refl.Types[id] = t
+		return t
+	}
+	decorationsOf := func(id uint32) Decorations {
+		if decs, ok := decorations[id]; ok {
+			return *decs
+		}
+		return Decorations{}
+	}
+
+	words := module.Words
+	for offset := spirvHeaderWords; offset < len(words); {
+		// Instruction header.
+		count := int(words[offset] >> 16)
+		opcode := words[offset] & 0xffff
+		if count == 0 || offset+count > len(words) {
+			return nil, fmt.Errorf("spirv: instruction at word %d has bad word count %d", offset, count)
+		}
+		ops := words[offset+1 : offset+count]
+		offset += count
+
+		// Minimum operands for each instruction used.
+		need := func(n int) error {
+			if len(ops) < n {
+				return fmt.Errorf("spirv: opcode %d at word %d has %d operands, expected at least %d",
+					opcode, offset-count, len(ops), n)
+			}
+			return nil
+		}
+
+		if function != 0 {
+			functionOperands[function] = append(functionOperands[function], ops...)
+		}
+
+		switch opcode {
+		case opFunction:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			function = ops[1]
+			functionOperands[function] = make([]uint32, 0)
+
+		case opFunctionEnd:
+			function = 0
+
+		case opName:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			names[ops[0]], _ = decodeString(ops[1:])
+
+		case opMemberName:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			if memberNames[ops[0]] == nil {
+				memberNames[ops[0]] = make(map[uint32]string)
+			}
+			memberNames[ops[0]][ops[1]], _ = decodeString(ops[2:])
+
+		case opEntryPoint:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			name, n := decodeString(ops[2:])
+			refl.EntryPoints = append(refl.EntryPoints, EntryPoint{
+				ID:    ops[1],
+				Name:  name,
+				Model: ExecutionModel(ops[0]),
+			})
+			interfaces = append(interfaces, ops[2+n:])
+
+		case opDecorate:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			if decorations[ops[0]] == nil {
+				decorations[ops[0]] = &Decorations{}
+			}
+			decorations[ops[0]].apply(ops[1], ops[2:])
+
+		case opMemberDecorate:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			if memberDecorations[ops[0]] == nil {
+				memberDecorations[ops[0]] = make(map[uint32]*Decorations)
+			}
+			if memberDecorations[ops[0]][ops[1]] == nil {
+				memberDecorations[ops[0]][ops[1]] = &Decorations{}
+			}
+			memberDecorations[ops[0]][ops[1]].apply(ops[2], ops[3:])
+
+		case opTypeVoid, opTypeBool, opTypeSampler, opTypeFunction:
+			if err := need(1); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind = map[uint32]TypeKind{
+				opTypeVoid:     TypeVoid,
+				opTypeBool:     TypeBool,
+				opTypeSampler:  TypeSampler,
+				opTypeFunction: TypeFunction,
+			}[opcode]
+
+		case opTypeInt:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Width, t.Signed = TypeInt, ops[1], ops[2] != 0
+
+		case opTypeFloat:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Width = TypeFloat, ops[1]
+
+		case opTypeVector, opTypeMatrix:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Elem, t.Count = TypeVector, typeOf(ops[1]), ops[2]
+			if opcode == opTypeMatrix {
+				t.Kind = TypeMatrix
+			}
+
+		case opTypeImage:
+			if err := need(8); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Elem, t.Dim, t.Sampled = TypeImage, typeOf(ops[1]), ops[2], ops[6]
+
+		case opTypeSampledImage:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Elem = TypeSampledImage, typeOf(ops[1])
+
+		case opTypeArray:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			length, ok := refl.Constants[ops[2]]
+			if !ok {
+				return nil, fmt.Errorf("spirv: array type %d has non-constant length id %d", ops[0], ops[2])
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Elem, t.Count = TypeArray, typeOf(ops[1]), length
+			t.Decorations = decorationsOf(ops[0])
+
+		case opTypeRuntimeArray:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.Elem = TypeRuntimeArray, typeOf(ops[1])
+			t.Decorations = decorationsOf(ops[0])
+
+		case opTypeStruct:
+			if err := need(1); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind = TypeStruct
+			t.Name = names[ops[0]]
+			t.Decorations = decorationsOf(ops[0])
+			t.Members = make([]*Type, len(ops)-1)
+			t.MemberNames = make([]string, len(ops)-1)
+			t.MemberDecorations = make([]Decorations, len(ops)-1)
+			for k, id := range ops[1:] {
+				t.Members[k] = typeOf(id)
+				t.MemberNames[k] = memberNames[ops[0]][uint32(k)]
+				if decs, ok := memberDecorations[ops[0]][uint32(k)]; ok {
+					t.MemberDecorations[k] = *decs
+				}
+			}
+
+		case opTypePointer:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			t := typeOf(ops[0])
+			t.Kind, t.StorageClass, t.Elem = TypePointer, StorageClass(ops[1]), typeOf(ops[2])
+
+		case opConstant, opSpecConstant:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			refl.Constants[ops[1]] = ops[2]
+
+		case opVariable:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+			ptr := typeOf(ops[0])
+			if ptr.Kind != TypePointer || ptr.Elem == nil {
+				return nil, fmt.Errorf("spirv: variable %d %s has result type %d, which is not a pointer type",
+					ops[1], names[ops[1]], ops[0])
+			}
+			refl.Variables[ops[1]] = &Variable{
+				ID:           ops[1],
+				Name:         names[ops[1]],
+				Type:         ptr.Elem,
+				StorageClass: StorageClass(ops[2]),
+				Decorations:  decorationsOf(ops[1]),
+			}
+		}
+	}
+
+	// Resolve the entry point interfaces and collect the IDs each entry
+	// point uses, following function calls.
+	for k := range refl.EntryPoints {
+		ep := &refl.EntryPoints[k]
+		ep.Uses = make(map[uint32]bool)
+		var use func(id uint32)
+		use = func(id uint32) {
+			if ep.Uses[id] {
+				return
+			}
+			ep.Uses[id] = true
+			for _, operand := range functionOperands[id] {
+				use(operand)
+			}
+		}
+		use(ep.ID)
+		for _, id := range interfaces[k] {
+			v, ok := refl.Variables[id]
+			if !ok {
+				return nil, fmt.Errorf("spirv: entry point %s lists unknown variable %d", ep.Name, id)
+			}
+			ep.Interface = append(ep.Interface, v)
+			ep.Uses[id] = true
+			switch v.StorageClass {
+			case StorageClassInput:
+				ep.Inputs = append(ep.Inputs, v)
+			case StorageClassOutput:
+				ep.Outputs = append(ep.Outputs, v)
+			}
+		}
+	}
+
+	return refl, nil
+}
+
+// The only entry point in the module.
+func (refl *Reflection) EntryPoint() (EntryPoint, error) {
+	if len(refl.EntryPoints) != 1 {
+		names := make([]string, len(refl.EntryPoints))
+		for k, ep := range refl.EntryPoints {
+			names[k] = fmt.Sprintf("%s(%s)", ep.Name, ep.Model)
+		}
+		return EntryPoint{}, fmt.Errorf("spirv: expected exactly one entry point, found %d %v",
+			len(refl.EntryPoints), names)
+	}
+	return refl.EntryPoints[0], nil
+}
+
+// The entry point with the given name and execution model. An empty name
+// selects the only entry point of the module.
+func (refl *Reflection) FindEntryPoint(name string, model ExecutionModel) (EntryPoint, error) {
+	// The only entry point.
+	if name == "" {
+		ep, err := refl.EntryPoint()
+		if err == nil && ep.Model != model {
+			err = fmt.Errorf("spirv: entry point %s is a %s shader, expected %s", ep.Name, ep.Model, model)
+		}
+		return ep, err
+	}
+
+	// Search by name.
+	names := make([]string, len(refl.EntryPoints))
+	for k, ep := range refl.EntryPoints {
+		if ep.Name == name && ep.Model == model {
+			return ep, nil
+		}
+		names[k] = fmt.Sprintf("%s(%s)", ep.Name, ep.Model)
+	}
+	return EntryPoint{}, fmt.Errorf("spirv: no %s entry point named %s, found %v", model, name, names)
+}
+
+// Decode a nul-terminated literal string, returning the string and the
+// number of words it used.
+func decodeString(words []uint32) (string, int) {
+	var sb strings.Builder
+	for k, w := range words {
+		for h := 0; h < 4; h++ {
+			c := byte(w >> (8 * h))
+			if c == 0 {
+				return sb.String(), k + 1
+			}
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), len(words)
+}
+
+// InterfaceMismatch is returned when an input of one stage doesn't match the
+// output of the stage before it. Members of interface blocks are reported
+// as variables named block.member.
+type InterfaceMismatch struct {
+	Location uint32
+	Producer EntryPoint
+	Consumer EntryPoint
+	Output   *Variable // nil if nothing is written at the location.
+	Input    *Variable
+}
+
+func (err *InterfaceMismatch) Error() string {
+	if err.Output == nil {
+		return fmt.Sprintf("location %d: %s input %s %s is not written by the %s stage",
+			err.Location,
+			err.Consumer.Model, err.Input.Type, err.Input.Name,
+			err.Producer.Model)
+	}
+	return fmt.Sprintf("location %d: %s output %s %s does not match %s input %s %s",
+		err.Location,
+		err.Producer.Model, err.Output.Type, err.Output.Name,
+		err.Consumer.Model, err.Input.Type, err.Input.Name)
+}
+
+// Reports if the stage's inputs, or outputs, have an extra per-vertex array
+// dimension.
+func (model ExecutionModel) arrayedInputs() bool {
+	return model == ExecutionModelTessellationControl ||
+		model == ExecutionModelTessellationEvaluation ||
+		model == ExecutionModelGeometry
+}
+
+func (model ExecutionModel) arrayedOutputs() bool {
+	return model == ExecutionModelTessellationControl
+}
+
+// The number of locations an interface variable of the type occupies.
+func interfaceLocations(t *Type) uint32 {
+	if t == nil {
+		return 1
+	}
+	switch t.Kind {
+	case TypeVector:
+		// 64-bit three and four component vectors use two.
+		if t.Elem != nil && t.Elem.Width == 64 && t.Count > 2 {
+			return 2
+		}
+	case TypeMatrix, TypeArray:
+		return t.Count * interfaceLocations(t.Elem)
+	case TypeStruct:
+		n := uint32(0)
+		for _, m := range t.Members {
+			n += interfaceLocations(m)
+		}
+		return n
+	}
+	return 1
+}
+
+// CheckStageInterface compares the user defined inputs of the consumer
+// stage with the outputs of the producer stage, location by location.
+// Interface blocks are compared member by member. An output may have more
+// vector components than the input reading it. Outputs that are not read
+// are allowed.
+func CheckStageInterface(producer, consumer EntryPoint) error {
+	// Strip the per-vertex array of arrayed interfaces and split blocks into
+	// a variable for each member. Members without a Location follow the
+	// member before them, the first one starts at the block's Location.
+	// Built-ins and variables without a location are left out.
+	interfaceVariables := func(v *Variable, arrayed bool) ([]*Variable, error) {
+		t := v.Type
+		if t != nil && arrayed && t.Kind == TypeArray {
+			t = t.Elem
+		}
+		if t == nil {
+			return nil, fmt.Errorf("spirv: interface variable %d %s has no type", v.ID, v.Name)
+		}
+		if t.Kind != TypeStruct {
+			if v.Decorations.BuiltIn.IsSet() || !v.Decorations.Location.IsSet() {
+				return nil, nil
+			}
+			flat := *v
+			flat.Type = t
+			return []*Variable{&flat}, nil
+		}
+
+		vars := make([]*Variable, 0, len(t.Members))
+		location, located := uint32(0), v.Decorations.Location.IsSet()
+		if located {
+			location = v.Decorations.Location.Val()
+		}
+		for k, m := range t.Members {
+			decs := t.MemberDecorations[k]
+			if decs.Location.IsSet() {
+				location, located = decs.Location.Val(), true
+			}
+			if m == nil {
+				return nil, fmt.Errorf("spirv: member %d of interface block %s has no type", k, v.Name)
+			}
+			if decs.BuiltIn.IsSet() || !located {
+				continue
+			}
+			member := &Variable{
+				ID:           v.ID,
+				Name:         t.MemberNames[k],
+				Type:         m,
+				StorageClass: v.StorageClass,
+				Decorations:  decs,
+			}
+			if v.Name != "" {
+				member.Name = v.Name + "." + member.Name
+			}
+			member.Decorations.Location.Set(location)
+			vars = append(vars, member)
+			location += interfaceLocations(m)
+		}
+		return vars, nil
+	}
+	type key struct{ location, component uint32 }
+	keyOf := func(v *Variable) key {
+		k := key{location: v.Decorations.Location.Val()}
+		if v.Decorations.Component.IsSet() {
+			k.component = v.Decorations.Component.Val()
+		}
+		return k
+	}
+
+	// Index the outputs by location.
+	outputs := make(map[key]*Variable)
+	for _, v := range producer.Outputs {
+		vars, err := interfaceVariables(v, producer.Model.arrayedOutputs())
+		if err != nil {
+			return err
+		}
+		for _, output := range vars {
+			outputs[keyOf(output)] = output
+		}
+	}
+
+	// Check each input against the output at its location.
+	for _, v := range consumer.Inputs {
+		vars, err := interfaceVariables(v, consumer.Model.arrayedInputs())
+		if err != nil {
+			return err
+		}
+		for _, input := range vars {
+			mismatch := &InterfaceMismatch{
+				Location: input.Decorations.Location.Val(),
+				Producer: producer,
+				Consumer: consumer,
+				Input:    input,
+			}
+			output, ok := outputs[keyOf(input)]
+			if !ok {
+				return mismatch
+			}
+			mismatch.Output = output
+
+			out, in := output.Type, input.Type
+			if out.Equal(in) {
+				continue
+			}
+			if out.Kind == TypeVector && in.Kind == TypeVector && out.Elem.Equal(in.Elem) && out.Count >= in.Count {
+				continue
+			}
+			return mismatch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,516 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A hand assembled SPIR-V module. Instructions are kept in the sections of
+// the logical layout, so they can be added in any order.
+type testModule struct {
+	bound       uint32
+	entryPoints []uint32
+	debug       []uint32
+	decorations []uint32
+	types       []uint32
+	functions   []uint32
+	typeIDs     map[string]uint32
+}
+
+func newTestModule() *testModule {
+	return &testModule{
+		bound:   1,
+		typeIDs: make(map[string]uint32),
+	}
+}
+
+// Append an instruction to a section.
+func instruction(section *[]uint32, opcode uint32, operands ...uint32) {
+	*section = append(*section, uint32(len(operands)+1)<<16|opcode)
+	*section = append(*section, operands...)
+}
+
+// Encode a nul-terminated literal string.
+func literalString(s string) []uint32 {
+	b := append([]byte(s), 0)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	words := make([]uint32, len(b)/4)
+	for k := range words {
+		words[k] = binary.LittleEndian.Uint32(b[k*4:])
+	}
+	return words
+}
+
+func (m *testModule) id() uint32 {
+	m.bound++
+	return m.bound - 1
+}
+
+// The ID of a type spelled void, void(), float, double, int, uint, vecN,
+// ivecN, uvecN, dvecN, matN or T[n], declaring it the first time.
+func (m *testModule) typeID(name string) uint32 {
+	if id, ok := m.typeIDs[name]; ok {
+		return id
+	}
+	var id uint32
+	switch {
+	case strings.HasSuffix(name, "]"):
+		open := strings.LastIndex(name, "[")
+		n, _ := strconv.Atoi(name[open+1 : len(name)-1])
+		elem, uint := m.typeID(name[:open]), m.typeID("uint")
+		length := m.id()
+		instruction(&m.types, opConstant, uint, length, uint32(n))
+		id = m.id()
+		instruction(&m.types, opTypeArray, id, elem, length)
+	case name == "void":
+		id = m.id()
+		instruction(&m.types, opTypeVoid, id)
+	case name == "void()":
+		void := m.typeID("void")
+		id = m.id()
+		instruction(&m.types, opTypeFunction, id, void)
+	case name == "float" || name == "double":
+		id = m.id()
+		instruction(&m.types, opTypeFloat, id, map[string]uint32{"float": 32, "double": 64}[name])
+	case name == "int" || name == "uint":
+		id = m.id()
+		instruction(&m.types, opTypeInt, id, 32, map[string]uint32{"int": 1, "uint": 0}[name])
+	case strings.HasPrefix(name, "mat"):
+		column := m.typeID("vec" + name[3:])
+		id = m.id()
+		instruction(&m.types, opTypeMatrix, id, column, uint32(name[3]-'0'))
+	case strings.HasSuffix(name[:len(name)-1], "vec"):
+		elem := m.typeID(map[string]string{"": "float", "i": "int", "u": "uint", "d": "double"}[name[:len(name)-4]])
+		id = m.id()
+		instruction(&m.types, opTypeVector, id, elem, uint32(name[len(name)-1]-'0'))
+	default:
+		panic("unknown test type " + name)
+	}
+	m.typeIDs[name] = id
+	return id
+}
+
+// Decorations of a test variable or block member.
+func location(n uint32) []uint32  { return []uint32{decorationLocation, n} }
+func component(n uint32) []uint32 { return []uint32{decorationComponent, n} }
+func builtIn(n uint32) []uint32   { return []uint32{decorationBuiltIn, n} }
+
+// A variable or block member of a test module.
+type testVar struct {
+	name    string
+	typ     string // nil declares a variable without a pointer type.
+	decs    [][]uint32
+	members []testVar // Makes the variable an interface block.
+}
+
+// Declare an interface block and return its type ID.
+func (m *testModule) block(name string, members []testVar) uint32 {
+	ids := make([]uint32, len(members))
+	for k, member := range members {
+		ids[k] = m.typeID(member.typ)
+	}
+	id := m.id()
+	instruction(&m.debug, opName, append([]uint32{id}, literalString(name)...)...)
+	instruction(&m.decorations, opDecorate, id, decorationBlock)
+	for k, member := range members {
+		instruction(&m.debug, opMemberName, append([]uint32{id, uint32(k)}, literalString(member.name)...)...)
+		for _, dec := range member.decs {
+			instruction(&m.decorations, opMemberDecorate, append([]uint32{id, uint32(k)}, dec...)...)
+		}
+	}
+	instruction(&m.types, opTypeStruct, append([]uint32{id}, ids...)...)
+	return id
+}
+
+// Declare a global variable and return its ID.
+func (m *testModule) variable(v testVar, storage StorageClass) uint32 {
+	var pointer uint32
+	switch {
+	case v.members != nil:
+		pointee := m.block(v.name+"Block", v.members)
+		pointer = m.id()
+		instruction(&m.types, opTypePointer, pointer, uint32(storage), pointee)
+	case v.typ == "nil":
+		pointer = m.typeID("float")
+	default:
+		pointee := m.typeID(v.typ)
+		pointer = m.id()
+		instruction(&m.types, opTypePointer, pointer, uint32(storage), pointee)
+	}
+	id := m.id()
+	instruction(&m.types, opVariable, pointer, id, uint32(storage))
+	instruction(&m.debug, opName, append([]uint32{id}, literalString(v.name)...)...)
+	for _, dec := range v.decs {
+		instruction(&m.decorations, opDecorate, append([]uint32{id}, dec...)...)
+	}
+	return id
+}
+
+// Declare an entry point named main, without a function body.
+func (m *testModule) entryPoint(model ExecutionModel, interfaces ...uint32) {
+	m.namedEntryPoint(model, "main", m.id(), interfaces...)
+}
+
+func (m *testModule) namedEntryPoint(model ExecutionModel, name string, function uint32, interfaces ...uint32) {
+	operands := append([]uint32{uint32(model), function}, literalString(name)...)
+	instruction(&m.entryPoints, opEntryPoint, append(operands, interfaces...)...)
+}
+
+// Define a void function and return its ID. Each instruction of the body is
+// an opcode followed by its operands.
+func (m *testModule) function(body ...[]uint32) uint32 {
+	void, signature := m.typeID("void"), m.typeID("void()")
+	id := m.id()
+	instruction(&m.functions, opFunction, void, id, 0, signature)
+	for _, inst := range body {
+		instruction(&m.functions, inst[0], inst[1:]...)
+	}
+	instruction(&m.functions, opFunctionEnd)
+	return id
+}
+
+// The module in little-endian bytes.
+func (m *testModule) Bytes() []byte {
+	words := []uint32{SPIRVMagic, 0x00010000, 0, m.bound, 0}
+	words = append(words, m.entryPoints...)
+	words = append(words, m.debug...)
+	words = append(words, m.decorations...)
+	words = append(words, m.types...)
+	words = append(words, m.functions...)
+	b := make([]byte, len(words)*4)
+	for k, w := range words {
+		binary.LittleEndian.PutUint32(b[k*4:], w)
+	}
+	return b
+}
+
+// Parse and reflect the module.
+func (m *testModule) reflect(t *testing.T) *Reflection {
+	t.Helper()
+	module, err := ParseSPIRV(m.Bytes())
+	if err != nil {
+		t.Fatalf("ParseSPIRV: %v", err)
+	}
+	refl, err := Reflect(module)
+	if err != nil {
+		t.Fatalf("Reflect: %v", err)
+	}
+	return refl
+}
+
+// Assemble a stage whose entry point lists the inputs and outputs.
+func testStage(t *testing.T, model ExecutionModel, inputs, outputs []testVar) EntryPoint {
+	t.Helper()
+	m := newTestModule()
+	ids := make([]uint32, 0, len(inputs)+len(outputs))
+	for _, v := range inputs {
+		ids = append(ids, m.variable(v, StorageClassInput))
+	}
+	for _, v := range outputs {
+		ids = append(ids, m.variable(v, StorageClassOutput))
+	}
+	m.entryPoint(model, ids...)
+	ep, err := m.reflect(t).FindEntryPoint("main", model)
+	if err != nil {
+		t.Fatalf("FindEntryPoint: %v", err)
+	}
+	return ep
+}
+
+func TestCheckStageInterface(t *testing.T) {
+	perVertex := testVar{name: "", members: []testVar{
+		{name: "gl_Position", typ: "vec4", decs: [][]uint32{builtIn(0)}},
+		{name: "gl_PointSize", typ: "float", decs: [][]uint32{builtIn(1)}},
+	}}
+	tests := []struct {
+		name     string
+		consumer ExecutionModel // Fragment if 0.
+		outputs  []testVar
+		inputs   []testVar
+		want     string // Part of the error, or empty for none.
+	}{
+		{
+			name: "matching",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+				{name: "fragUV", typ: "vec2", decs: [][]uint32{location(1)}},
+			},
+			inputs: []testVar{
+				{name: "inUV", typ: "vec2", decs: [][]uint32{location(1)}},
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+		},
+		{
+			name: "unread output",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+				{name: "fragExtra", typ: "vec4", decs: [][]uint32{location(5)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+		},
+		{
+			name: "missing location",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(1)}},
+			},
+			want: "location 1: Fragment input vec3 inColor is not written by the Vertex stage",
+		},
+		{
+			name: "vector type mismatch",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "ivec3", decs: [][]uint32{location(0)}},
+			},
+			want: "location 0: Vertex output vec3 fragColor does not match Fragment input ivec3 inColor",
+		},
+		{
+			name: "scalar type mismatch",
+			outputs: []testVar{
+				{name: "fragID", typ: "float", decs: [][]uint32{location(2)}},
+			},
+			inputs: []testVar{
+				{name: "inID", typ: "int", decs: [][]uint32{location(2)}},
+			},
+			want: "location 2: Vertex output float32 fragID does not match Fragment input int32 inID",
+		},
+		{
+			name: "fewer input components",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec4", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+		},
+		{
+			name: "more input components",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec2", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+			want: "location 0: Vertex output vec2 fragColor does not match Fragment input vec3 inColor",
+		},
+		{
+			name: "component",
+			outputs: []testVar{
+				{name: "fragX", typ: "float", decs: [][]uint32{location(0)}},
+				{name: "fragY", typ: "float", decs: [][]uint32{location(0), component(1)}},
+			},
+			inputs: []testVar{
+				{name: "inY", typ: "float", decs: [][]uint32{location(0), component(1)}},
+			},
+		},
+		{
+			name: "missing component",
+			outputs: []testVar{
+				{name: "fragX", typ: "float", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inZ", typ: "float", decs: [][]uint32{location(0), component(2)}},
+			},
+			want: "location 0: Fragment input float32 inZ is not written",
+		},
+		{
+			name: "built-ins",
+			outputs: []testVar{
+				perVertex,
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "gl_FragCoord", typ: "vec4", decs: [][]uint32{builtIn(15)}},
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+		},
+		{
+			name: "built-in is not a location",
+			outputs: []testVar{
+				perVertex,
+			},
+			inputs: []testVar{
+				{name: "inPosition", typ: "vec4", decs: [][]uint32{location(0)}},
+			},
+			want: "location 0: Fragment input vec4 inPosition is not written",
+		},
+		{
+			name: "block member locations",
+			outputs: []testVar{
+				{name: "vOut", members: []testVar{
+					{name: "color", typ: "vec3", decs: [][]uint32{location(1)}},
+					{name: "uv", typ: "vec2"},
+				}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3", decs: [][]uint32{location(1)}},
+				{name: "inUV", typ: "vec2", decs: [][]uint32{location(2)}},
+			},
+		},
+		{
+			name: "block at a location",
+			outputs: []testVar{
+				{name: "vOut", decs: [][]uint32{location(3)}, members: []testVar{
+					{name: "color", typ: "vec4"},
+					{name: "basis", typ: "mat2"},
+					{name: "depth", typ: "float"},
+				}},
+			},
+			inputs: []testVar{
+				{name: "inBasis", typ: "mat2", decs: [][]uint32{location(4)}},
+				{name: "inDepth", typ: "float", decs: [][]uint32{location(6)}},
+			},
+		},
+		{
+			name: "block member mismatch",
+			outputs: []testVar{
+				{name: "vOut", members: []testVar{
+					{name: "color", typ: "vec3", decs: [][]uint32{location(1)}},
+					{name: "uv", typ: "vec2"},
+				}},
+			},
+			inputs: []testVar{
+				{name: "inUV", typ: "ivec2", decs: [][]uint32{location(2)}},
+			},
+			want: "location 2: Vertex output vec2 vOut.uv does not match Fragment input ivec2 inUV",
+		},
+		{
+			name: "input block",
+			outputs: []testVar{
+				{name: "fragColor", typ: "vec3", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "fIn", members: []testVar{
+					{name: "color", typ: "vec3", decs: [][]uint32{location(0)}},
+					{name: "uv", typ: "vec2"},
+				}},
+			},
+			want: "location 1: Fragment input vec2 fIn.uv is not written",
+		},
+		{
+			name:     "arrayed inputs",
+			consumer: ExecutionModelGeometry,
+			outputs: []testVar{
+				{name: "vColor", typ: "vec4", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec4[3]", decs: [][]uint32{location(0)}},
+			},
+		},
+		{
+			name:     "arrayed input mismatch",
+			consumer: ExecutionModelGeometry,
+			outputs: []testVar{
+				{name: "vColor", typ: "vec2", decs: [][]uint32{location(0)}},
+			},
+			inputs: []testVar{
+				{name: "inColor", typ: "vec3[3]", decs: [][]uint32{location(0)}},
+			},
+			want: "location 0: Vertex output vec2 vColor does not match Geometry input vec3 inColor",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			consumerModel := test.consumer
+			if consumerModel == 0 {
+				consumerModel = ExecutionModelFragment
+			}
+			producer := testStage(t, ExecutionModelVertex, nil, test.outputs)
+			consumer := testStage(t, consumerModel, test.inputs, nil)
+
+			err := CheckStageInterface(producer, consumer)
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.want != "" && err == nil:
+				t.Errorf("expected an error containing %q", test.want)
+			case test.want != "" && !strings.Contains(err.Error(), test.want):
+				t.Errorf("error %q does not contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestReflectVariableNotPointer(t *testing.T) {
+	// The variable's result type is a float instead of a pointer, which
+	// would leave it without a type.
+	for _, storage := range []StorageClass{StorageClassInput, StorageClassUniform, StorageClassPushConstant} {
+		m := newTestModule()
+		m.variable(testVar{name: "value", typ: "nil"}, storage)
+		module, err := ParseSPIRV(m.Bytes())
+		if err != nil {
+			t.Fatalf("ParseSPIRV: %v", err)
+		}
+		_, err = Reflect(module)
+		want := "value has result type"
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "which is not a pointer type") {
+			t.Errorf("storage class %d: expected an error containing %q, got %v", storage, want, err)
+		}
+	}
+}
+
+// Reflect a compiled shader and find its main entry point, skipping the
+// test if the shader isn't compiled.
+func shaderEntryPoint(t *testing.T, fn string, model ExecutionModel) EntryPoint {
+	t.Helper()
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Skipf("%s is not compiled: %v", fn, err)
+	}
+	module, err := ParseSPIRV(data)
+	if err != nil {
+		t.Fatalf("%s: %v", fn, err)
+	}
+	refl, err := Reflect(module)
+	if err != nil {
+		t.Fatalf("%s: %v", fn, err)
+	}
+	ep, err := refl.FindEntryPoint("main", model)
+	if err != nil {
+		t.Fatalf("%s: %v", fn, err)
+	}
+	return ep
+}
+
+func TestCheckStageInterfaceShaders(t *testing.T) {
+	// The shaders are compiled outside of the Go build.
+	tests := []struct {
+		vertex, fragment string
+	}{
+		{"../shaders/vert.spv", "../shaders/frag.spv"},
+		{"../shaders/meshvert.spv", "../shaders/frag.spv"},
+		{"../shaders/spinvert.spv", "../shaders/frag.spv"},
+	}
+	for _, test := range tests {
+		t.Run(filepath.Base(test.vertex), func(t *testing.T) {
+			vertex := shaderEntryPoint(t, test.vertex, ExecutionModelVertex)
+			fragment := shaderEntryPoint(t, test.fragment, ExecutionModelFragment)
+			if err := CheckStageInterface(vertex, fragment); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// The fragment shader reads the color at location 0, which a vertex
+	// shader without outputs doesn't write.
+	t.Run("mismatch", func(t *testing.T) {
+		fragment := shaderEntryPoint(t, "../shaders/frag.spv", ExecutionModelFragment)
+		vertex := testStage(t, ExecutionModelVertex, nil, nil)
+		want := "location 0: Fragment input vec3 fragColor is not written by the Vertex stage"
+		if err := CheckStageInterface(vertex, fragment); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, got %v", want, err)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Record and submit a command buffer, then wait for the queue to finish it.
+func submitOneTime(device vk.Device, pool vk.CommandPool, queue vk.Queue, record func(vk.CommandBuffer)) error {
+	// Create the info object.
+	buffersInfo := vk.CommandBufferAllocateInfo{
+		SType:              vk.StructureTypeCommandBufferAllocateInfo,
+		CommandPool:        pool,
+		Level:              vk.CommandBufferLevelPrimary,
+		CommandBufferCount: 1,
+	}
+
+	// Call the Vulkan function.
+	buffers := make([]vk.CommandBuffer, 1)
+	err := CheckResult("vkAllocateCommandBuffers", vk.AllocateCommandBuffers(device, &buffersInfo, buffers))
+	if err != nil {
+		return err
+	}
+	defer vk.FreeCommandBuffers(device, pool, 1, buffers)
+
+	// Record the commands.
+	err = CheckResult("vkBeginCommandBuffer", vk.BeginCommandBuffer(buffers[0], &vk.CommandBufferBeginInfo{
+		SType: vk.StructureTypeCommandBufferBeginInfo,
+		Flags: vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit),
+	}))
+	if err != nil {
+		return err
+	}
+	record(buffers[0])
+	err = CheckResult("vkEndCommandBuffer", vk.EndCommandBuffer(buffers[0]))
+	if err != nil {
+		return err
+	}
+
+	// Submit and wait.
+	submitInfos := []vk.SubmitInfo{
+		vk.SubmitInfo{
+			SType:              vk.StructureTypeSubmitInfo,
+			CommandBufferCount: 1,
+			PCommandBuffers:    buffers,
+		},
+	}
+	err = CheckResult("vkQueueSubmit", vk.QueueSubmit(queue, 1, submitInfos, vk.Fence(vk.NullHandle)))
+	if err != nil {
+		return err
+	}
+	return CheckResult("vkQueueWaitIdle", vk.QueueWaitIdle(queue))
+}
+
+// Create an image and its view for use as a framebuffer attachment that
+// only lives during a render pass, such as a depth or multisampled image.
+func newAttachmentImage(allocator *Allocator, format vk.Format, extent vk.Extent2D, samples vk.SampleCountFlagBits, usage vk.ImageUsageFlags, aspect vk.ImageAspectFlags) (vk.Image, MemoryAllocation, vk.ImageView, error) {
+	// Create the info object.
+	imageInfo := vk.ImageCreateInfo{
+		SType:     vk.StructureTypeImageCreateInfo,
+		ImageType: vk.ImageType2d,
+		Format:    format,
+		Extent: vk.Extent3D{
+			Width:  extent.Width,
+			Height: extent.Height,
+			Depth:  1,
+		},
+		MipLevels:     1,
+		ArrayLayers:   1,
+		Samples:       samples,
+		Tiling:        vk.ImageTilingOptimal,
+		Usage:         usage,
+		SharingMode:   vk.SharingModeExclusive,
+		InitialLayout: vk.ImageLayoutUndefined,
+	}
+
+	// Create the image. Transient attachments can use lazily allocated
+	// memory where the device has it.
+	preferred := vk.MemoryPropertyFlags(0)
+	if usage&vk.ImageUsageFlags(vk.ImageUsageTransientAttachmentBit) != 0 {
+		preferred = vk.MemoryPropertyFlags(vk.MemoryPropertyLazilyAllocatedBit)
+	}
+	img, alloc, err := allocator.AllocateImage(imageInfo,
+		vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit),
+		preferred)
+	if err != nil {
+		return img, alloc, vk.ImageView(vk.NullHandle), err
+	}
+
+	// Create the info object.
+	imageViewInfo := vk.ImageViewCreateInfo{
+		SType:    vk.StructureTypeImageViewCreateInfo,
+		Image:    img,
+		ViewType: vk.ImageViewType2d,
+		Format:   format,
+		SubresourceRange: vk.ImageSubresourceRange{
+			AspectMask:     aspect,
+			BaseMipLevel:   0,
+			LevelCount:     1,
+			BaseArrayLayer: 0,
+			LayerCount:     1,
+		},
+	}
+
+	// Call the Vulkan function.
+	var imgView vk.ImageView
+	err = CheckResultInfo("vkCreateImageView", vk.CreateImageView(allocator.device, &imageViewInfo, nil, &imgView),
+		fmt.Sprintf("format=%d samples=%d", format, samples))
+	return img, alloc, imgView, err
+}
@@ -0,0 +1,83 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// SPIR-V header values.
+const (
+	SPIRVMagic        = 0x07230203
+	spirvHeaderWords  = 5
+	spirvMaxMinorVers = 6
+)
+
+// A validated SPIR-V module.
+type SPIRV struct {
+	Words     WordsUint32 // The module, in host word order.
+	Major     uint8       // SPIR-V version.
+	Minor     uint8
+	Generator uint32 // Tool ID in the high 16 bits, tool version in the low.
+	Bound     uint32 // All IDs in the module are less than Bound.
+	Swapped   bool   // The module was stored with the opposite endianness.
+}
+
+// ParseSPIRV validates the header of a SPIR-V module and returns its words
+// in host order, byte swapping them if the module was written big-endian.
+func ParseSPIRV(b []byte) (*SPIRV, error) {
+	// Length checks.
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("spirv: length %d is not a multiple of 4", len(b))
+	}
+	if len(b) < spirvHeaderWords*4 {
+		return nil, fmt.Errorf("spirv: length %d is shorter than the %d byte header", len(b), spirvHeaderWords*4)
+	}
+
+	// Decode the words.
+	words := make(WordsUint32, len(b)/4)
+	for k := range words {
+		words[k] = binary.LittleEndian.Uint32(b[k*4:])
+	}
+
+	// Magic number, swapping the words if the module is the other endian.
+	module := &SPIRV{Words: words}
+	switch words[0] {
+	case SPIRVMagic:
+	case bits.ReverseBytes32(SPIRVMagic):
+		for k := range words {
+			words[k] = bits.ReverseBytes32(words[k])
+		}
+		module.Swapped = true
+	default:
+		return nil, fmt.Errorf("spirv: bad magic number %#08x, expected %#08x", words[0], SPIRVMagic)
+	}
+
+	// Version.
+	version := words[1]
+	module.Major = uint8(version >> 16)
+	module.Minor = uint8(version >> 8)
+	if version&0xff0000ff != 0 || module.Major != 1 || module.Minor > spirvMaxMinorVers {
+		return nil, fmt.Errorf("spirv: unsupported version word %#08x", version)
+	}
+
+	// Generator, bound and schema.
+	module.Generator = words[2]
+	module.Bound = words[3]
+	if module.Bound == 0 {
+		return nil, fmt.Errorf("spirv: id bound is 0")
+	}
+	if words[4] != 0 {
+		return nil, fmt.Errorf("spirv: reserved schema word is %d, expected 0", words[4])
+	}
+
+	return module, nil
+}
+
+func (module *SPIRV) String() string {
+	return fmt.Sprintf("SPIR-V %d.%d generator=%#08x bound=%d words=%d",
+		module.Major, module.Minor,
+		module.Generator,
+		module.Bound,
+		len(module.Words))
+}
@@ -0,0 +1,134 @@
+package renderer
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// Encode words with the byte order.
+func spirvBytes(order binary.ByteOrder, words ...uint32) []byte {
+	b := make([]byte, len(words)*4)
+	for k, w := range words {
+		order.PutUint32(b[k*4:], w)
+	}
+	return b
+}
+
+func TestParseSPIRV(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string // Part of the error, or empty for none.
+		swapped bool
+	}{
+		{
+			name: "little endian",
+			data: spirvBytes(le, SPIRVMagic, 0x00010300, 0x00080001, 12, 0),
+		},
+		{
+			name:    "big endian",
+			data:    spirvBytes(be, SPIRVMagic, 0x00010300, 0x00080001, 12, 0),
+			swapped: true,
+		},
+		{
+			name: "bad magic",
+			data: spirvBytes(le, 0x07230204, 0x00010000, 0, 12, 0),
+			want: "bad magic number 0x07230204, expected 0x07230203",
+		},
+		{
+			name: "empty",
+			data: nil,
+			want: "length 0 is shorter than the 20 byte header",
+		},
+		{
+			name: "truncated header",
+			data: spirvBytes(le, SPIRVMagic, 0x00010000, 0, 12),
+			want: "length 16 is shorter than the 20 byte header",
+		},
+		{
+			name: "bad length",
+			data: append(spirvBytes(le, SPIRVMagic, 0x00010000, 0, 12, 0), 0x11, 0x00),
+			want: "length 22 is not a multiple of 4",
+		},
+		{
+			name: "unsupported major version",
+			data: spirvBytes(le, SPIRVMagic, 0x00020000, 0, 12, 0),
+			want: "unsupported version word 0x00020000",
+		},
+		{
+			name: "unsupported minor version",
+			data: spirvBytes(le, SPIRVMagic, 0x00010700, 0, 12, 0),
+			want: "unsupported version word 0x00010700",
+		},
+		{
+			name: "zero bound",
+			data: spirvBytes(le, SPIRVMagic, 0x00010000, 0, 0, 0),
+			want: "id bound is 0",
+		},
+		{
+			name: "reserved schema",
+			data: spirvBytes(le, SPIRVMagic, 0x00010000, 0, 12, 1),
+			want: "reserved schema word is 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, err := ParseSPIRV(test.data)
+			if test.want != "" {
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Fatalf("expected an error containing %q, got %v", test.want, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if module.Words[0] != SPIRVMagic || module.Major != 1 || module.Minor != 3 ||
+				module.Generator != 0x00080001 || module.Bound != 12 {
+				t.Errorf("decoded %s, words %#x", module, module.Words)
+			}
+			if module.Swapped != test.swapped {
+				t.Errorf("Swapped = %v, expected %v", module.Swapped, test.swapped)
+			}
+		})
+	}
+}
+
+func TestReflectTruncated(t *testing.T) {
+	header := []uint32{SPIRVMagic, 0x00010000, 0, 12, 0}
+	tests := []struct {
+		name  string
+		words []uint32
+		want  string
+	}{
+		{
+			name:  "instruction past the end",
+			words: []uint32{4<<16 | opTypeInt, 1, 32},
+			want:  "instruction at word 5 has bad word count 4",
+		},
+		{
+			name:  "zero word count",
+			words: []uint32{0<<16 | opTypeVoid},
+			want:  "instruction at word 5 has bad word count 0",
+		},
+		{
+			name:  "missing operands",
+			words: []uint32{3<<16 | opTypeInt, 1, 32},
+			want:  "opcode 21 at word 5 has 2 operands, expected at least 3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, err := ParseSPIRV(spirvBytes(binary.LittleEndian, append(header, test.words...)...))
+			if err != nil {
+				t.Fatalf("ParseSPIRV: %v", err)
+			}
+			_, err = Reflect(module)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Surface format preference lists, most preferred first.
+var (
+	// 8-bit sRGB, with the hardware doing the gamma encoding.
+	SurfaceFormatsSRGB = []vk.SurfaceFormat{
+		{Format: vk.FormatB8g8r8a8Srgb, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+		{Format: vk.FormatR8g8b8a8Srgb, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+	}
+
+	// UNORM formats, for applications that apply gamma themselves.
+	SurfaceFormatsUNORM = []vk.SurfaceFormat{
+		{Format: vk.FormatB8g8r8a8Unorm, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+		{Format: vk.FormatR8g8b8a8Unorm, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+	}
+
+	// 10-bit UNORM formats in the sRGB color space.
+	SurfaceFormats10Bit = []vk.SurfaceFormat{
+		{Format: vk.FormatA2b10g10r10UnormPack32, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+		{Format: vk.FormatA2r10g10b10UnormPack32, ColorSpace: vk.ColorSpaceSrgbNonlinear},
+	}
+
+	// HDR10, 10-bit with the ST 2084 (PQ) transfer function.
+	SurfaceFormatsHDR10 = []vk.SurfaceFormat{
+		{Format: vk.FormatA2b10g10r10UnormPack32, ColorSpace: vk.ColorSpaceHdr10St2084},
+		{Format: vk.FormatA2r10g10b10UnormPack32, ColorSpace: vk.ColorSpaceHdr10St2084},
+	}
+
+	// scRGB, linear half floats that may go outside of 0 to 1.
+	SurfaceFormatsScRGB = []vk.SurfaceFormat{
+		{Format: vk.FormatR16g16b16a16Sfloat, ColorSpace: vk.ColorSpaceExtendedSrgbLinear},
+	}
+
+	// The preferences used when the application doesn't have any.
+	DefaultSurfaceFormats = SurfaceFormatsSRGB
+)
+
+var formatNames = map[vk.Format]string{
+	vk.FormatB8g8r8a8Srgb:           "B8G8R8A8_SRGB",
+	vk.FormatR8g8b8a8Srgb:           "R8G8B8A8_SRGB",
+	vk.FormatB8g8r8a8Unorm:          "B8G8R8A8_UNORM",
+	vk.FormatR8g8b8a8Unorm:          "R8G8B8A8_UNORM",
+	vk.FormatA2b10g10r10UnormPack32: "A2B10G10R10_UNORM_PACK32",
+	vk.FormatA2r10g10b10UnormPack32: "A2R10G10B10_UNORM_PACK32",
+	vk.FormatR16g16b16a16Sfloat:     "R16G16B16A16_SFLOAT",
+}
+
+var colorSpaceNames = map[vk.ColorSpace]string{
+	vk.ColorSpaceSrgbNonlinear:         "SRGB_NONLINEAR",
+	vk.ColorSpaceDisplayP3Nonlinear:    "DISPLAY_P3_NONLINEAR",
+	vk.ColorSpaceExtendedSrgbLinear:    "EXTENDED_SRGB_LINEAR",
+	vk.ColorSpaceExtendedSrgbNonlinear: "EXTENDED_SRGB_NONLINEAR",
+	vk.ColorSpaceHdr10St2084:           "HDR10_ST2084",
+	vk.ColorSpacePassThrough:           "PASS_THROUGH",
+}
+
+// Name of a surface format for messages.
+func SurfaceFormatName(format vk.SurfaceFormat) string {
+	fName, ok := formatNames[format.Format]
+	if !ok {
+		fName = fmt.Sprintf("format(%d)", format.Format)
+	}
+	csName, ok := colorSpaceNames[format.ColorSpace]
+	if !ok {
+		csName = fmt.Sprintf("colorSpace(%d)", format.ColorSpace)
+	}
+	return fName + "/" + csName
+}
+
+// Reports if any of the formats need VK_EXT_swapchain_colorspace.
+func NeedsExtendedColorSpaces(formats []vk.SurfaceFormat) bool {
+	for _, format := range formats {
+		if format.ColorSpace != vk.ColorSpaceSrgbNonlinear {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseSurfaceFormat picks the first preference the surface supports and
+// explains the choice. Color spaces other than sRGB are skipped unless
+// extended color spaces are enabled. If no preference is supported the
+// first available format is used.
+func ChooseSurfaceFormat(available, preferences []vk.SurfaceFormat, extendedColorSpaces bool) (vk.SurfaceFormat, string) {
+	if len(preferences) == 0 {
+		preferences = DefaultSurfaceFormats
+	}
+
+	// A single undefined format means the surface takes any format.
+	anyFormat := len(available) == 1 && available[0].Format == vk.FormatUndefined
+
+	// Work through the preferences in order.
+	skipped := 0
+	skippedNote := func() string {
+		if skipped == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" (%d skipped, %s is not enabled)", skipped, vk.ExtSwapchainColorspaceExtensionName)
+	}
+	for k, pref := range preferences {
+		if pref.ColorSpace != vk.ColorSpaceSrgbNonlinear && !extendedColorSpaces {
+			skipped++
+			continue
+		}
+		if anyFormat {
+			return pref, fmt.Sprintf("%s is preference %d of %d%s, the surface accepts any format",
+				SurfaceFormatName(pref), k+1, len(preferences), skippedNote())
+		}
+		for _, v := range available {
+			if v == pref {
+				return v, fmt.Sprintf("%s is preference %d of %d%s",
+					SurfaceFormatName(v), k+1, len(preferences), skippedNote())
+			}
+		}
+	}
+
+	// Fall back to what the surface likes.
+	reason := fmt.Sprintf("none of the %d preferences are supported%s", len(preferences), skippedNote())
+	if len(available) == 0 || anyFormat {
+		return SurfaceFormatsSRGB[0], reason + ", using " + SurfaceFormatName(SurfaceFormatsSRGB[0])
+	}
+	return available[0], reason + ", using the surface's first format " + SurfaceFormatName(available[0])
+}
@@ -0,0 +1,98 @@
+package renderer
+
+import (
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestChooseSurfaceFormat(t *testing.T) {
+	srgb := vk.SurfaceFormat{Format: vk.FormatB8g8r8a8Srgb, ColorSpace: vk.ColorSpaceSrgbNonlinear}
+	rgbaSRGB := vk.SurfaceFormat{Format: vk.FormatR8g8b8a8Srgb, ColorSpace: vk.ColorSpaceSrgbNonlinear}
+	unorm := vk.SurfaceFormat{Format: vk.FormatB8g8r8a8Unorm, ColorSpace: vk.ColorSpaceSrgbNonlinear}
+	hdr10 := vk.SurfaceFormat{Format: vk.FormatA2b10g10r10UnormPack32, ColorSpace: vk.ColorSpaceHdr10St2084}
+	anyFormat := []vk.SurfaceFormat{{Format: vk.FormatUndefined, ColorSpace: vk.ColorSpaceSrgbNonlinear}}
+
+	tests := []struct {
+		name        string
+		available   []vk.SurfaceFormat
+		preferences []vk.SurfaceFormat
+		extended    bool
+		want        vk.SurfaceFormat
+		reason      string
+	}{
+		{
+			name:        "first preference",
+			available:   []vk.SurfaceFormat{unorm, srgb},
+			preferences: SurfaceFormatsSRGB,
+			want:        srgb,
+			reason:      "B8G8R8A8_SRGB/SRGB_NONLINEAR is preference 1 of 2",
+		},
+		{
+			name:        "second preference",
+			available:   []vk.SurfaceFormat{unorm, rgbaSRGB},
+			preferences: SurfaceFormatsSRGB,
+			want:        rgbaSRGB,
+			reason:      "R8G8B8A8_SRGB/SRGB_NONLINEAR is preference 2 of 2",
+		},
+		{
+			name:      "default preferences",
+			available: []vk.SurfaceFormat{srgb},
+			want:      srgb,
+			reason:    "B8G8R8A8_SRGB/SRGB_NONLINEAR is preference 1 of 2",
+		},
+		{
+			name:        "any format",
+			available:   anyFormat,
+			preferences: SurfaceFormatsUNORM,
+			want:        unorm,
+			reason:      "B8G8R8A8_UNORM/SRGB_NONLINEAR is preference 1 of 2, the surface accepts any format",
+		},
+		{
+			name:        "extended color space",
+			available:   []vk.SurfaceFormat{srgb, hdr10},
+			preferences: append(append([]vk.SurfaceFormat{}, SurfaceFormatsHDR10...), srgb),
+			extended:    true,
+			want:        hdr10,
+			reason:      "A2B10G10R10_UNORM_PACK32/HDR10_ST2084 is preference 1 of 3",
+		},
+		{
+			name:        "extended color space skipped",
+			available:   []vk.SurfaceFormat{srgb, hdr10},
+			preferences: append(append([]vk.SurfaceFormat{}, SurfaceFormatsHDR10...), srgb),
+			want:        srgb,
+			reason:      "B8G8R8A8_SRGB/SRGB_NONLINEAR is preference 3 of 3 (2 skipped, VK_EXT_swapchain_colorspace is not enabled)",
+		},
+		{
+			name:        "every preference skipped with any format",
+			available:   anyFormat,
+			preferences: SurfaceFormatsHDR10,
+			want:        srgb,
+			reason:      "none of the 2 preferences are supported (2 skipped, VK_EXT_swapchain_colorspace is not enabled), using B8G8R8A8_SRGB/SRGB_NONLINEAR",
+		},
+		{
+			name:        "fall back to the first available",
+			available:   []vk.SurfaceFormat{unorm, hdr10},
+			preferences: SurfaceFormatsSRGB,
+			want:        unorm,
+			reason:      "none of the 2 preferences are supported, using the surface's first format B8G8R8A8_UNORM/SRGB_NONLINEAR",
+		},
+		{
+			name:        "nothing available",
+			available:   nil,
+			preferences: SurfaceFormatsUNORM,
+			want:        srgb,
+			reason:      "none of the 2 preferences are supported, using B8G8R8A8_SRGB/SRGB_NONLINEAR",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, reason := ChooseSurfaceFormat(test.available, test.preferences, test.extended)
+			if got != test.want || reason != test.reason {
+				t.Errorf("got %s, %q\nexpected %s, %q",
+					SurfaceFormatName(got), reason,
+					SurfaceFormatName(test.want), test.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package renderer
+
+import (
+	"fmt"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// SwapchainOptions are the application's preferences for the swapchain.
+type SwapchainOptions struct {
+	SurfaceFormats      []vk.SurfaceFormat // Most preferred first, DefaultSurfaceFormats if empty.
+	ExtendedColorSpaces bool               // VK_EXT_swapchain_colorspace is enabled on the instance.
+	PresentPolicy       PresentPolicy
+	ImageCount          uint32 // Zero for one more than the surface's minimum.
+}
+
+// Swapchain owns the images that render passes draw into and their views.
+// With a null surface it owns a single offscreen image instead, so the same
+// code paths work headless.
+type Swapchain struct {
+	Handle      vk.Swapchain
+	Images      []vk.Image
+	ImageFormat vk.Format
+	ColorSpace  vk.ColorSpace
+	Extent      vk.Extent2D
+	ImageViews  []vk.ImageView
+
+	Options      SwapchainOptions
+	FormatReason string // Why ImageFormat and ColorSpace were chosen.
+
+	PresentMode       vk.PresentMode
+	PresentModeReason string // Why PresentMode was chosen.
+
+	device         vk.Device
+	allocator      *Allocator
+	physicalDevice PhysicalDevice
+	surface        vk.Surface
+
+	offscreenAllocation MemoryAllocation
+}
+
+// NewSwapchain creates a swapchain for the surface, or an offscreen image if
+// the surface is null, with memory from the allocator. extent is used when
+// the surface doesn't dictate the size of its images.
+func NewSwapchain(device vk.Device, allocator *Allocator, physicalDevice PhysicalDevice, surface vk.Surface, extent vk.Extent2D, options SwapchainOptions) (*Swapchain, error) {
+	// Create the result object.
+	swapchain := &Swapchain{
+		Options:        options,
+		device:         device,
+		allocator:      allocator,
+		physicalDevice: physicalDevice,
+		surface:        surface,
+	}
+
+	// Create the images.
+	if err := swapchain.create(extent, vk.Swapchain(vk.NullHandle)); err != nil {
+		swapchain.Cleanup()
+		return nil, err
+	}
+	return swapchain, nil
+}
+
+// ChooseImageCount returns the number of images to ask the surface for. A
+// requested count of zero means one more than the minimum, so the
+// application isn't kept waiting on the driver. Requests outside of what the
+// surface supports are an error.
+func ChooseImageCount(caps vk.SurfaceCapabilities, requested uint32) (uint32, error) {
+	if requested == 0 {
+		count := caps.MinImageCount + 1
+		if caps.MaxImageCount > 0 {
+			count = MinUint32(count, caps.MaxImageCount)
+		}
+		return count, nil
+	}
+	if requested < caps.MinImageCount {
+		return 0, fmt.Errorf("%d swapchain images requested, the surface needs at least %d",
+			requested, caps.MinImageCount)
+	}
+	if caps.MaxImageCount > 0 && requested > caps.MaxImageCount {
+		return 0, fmt.Errorf("%d swapchain images requested, the surface supports %d to %d",
+			requested, caps.MinImageCount, caps.MaxImageCount)
+	}
+	return requested, nil
+}
+
+// Reports if the swapchain renders to an offscreen image.
+func (swapchain *Swapchain) Offscreen() bool {
+	return swapchain.surface == vk.Surface(vk.NullHandle)
+}
+
+// The offscreen image, or a null handle if the swapchain presents.
+func (swapchain *Swapchain) OffscreenImage() vk.Image {
+	if !swapchain.Offscreen() || len(swapchain.Images) == 0 {
+		return vk.Image(vk.NullHandle)
+	}
+	return swapchain.Images[0]
+}
+
+// Recreate the swapchain for a new extent. The old swapchain is handed over
+// to the new one and then destroyed. Objects created from the old image
+// views, such as framebuffers, must be destroyed first.
+func (swapchain *Swapchain) Recreate(extent vk.Extent2D) error {
+	oldHandle := swapchain.Handle
+	swapchain.cleanupImages()
+	swapchain.Handle = vk.Swapchain(vk.NullHandle)
+	err := swapchain.create(extent, oldHandle)
+	vk.DestroySwapchain(swapchain.device, oldHandle, nil)
+	return err
+}
+
+// Destroy the swapchain and its images.
+func (swapchain *Swapchain) Cleanup() {
+	swapchain.cleanupImages()
+	vk.DestroySwapchain(swapchain.device, swapchain.Handle, nil)
+	swapchain.Handle = vk.Swapchain(vk.NullHandle)
+}
+
+func (swapchain *Swapchain) cleanupImages() {
+	for _, imgView := range swapchain.ImageViews {
+		vk.DestroyImageView(swapchain.device, imgView, nil)
+	}
+	swapchain.ImageViews = nil
+
+	// Offscreen images are owned by us, swapchain images by the swapchain.
+	if swapchain.Offscreen() {
+		vk.DestroyImage(swapchain.device, swapchain.OffscreenImage(), nil)
+		swapchain.allocator.Free(swapchain.offscreenAllocation)
+		swapchain.offscreenAllocation = MemoryAllocation{}
+	}
+	swapchain.Images = nil
+}
+
+func (swapchain *Swapchain) create(requestedExtent vk.Extent2D, oldSwapchain vk.Swapchain) error {
+	device, physicalDevice, surface := swapchain.device, swapchain.physicalDevice, swapchain.surface
+
+	newSwapchain := func() (vk.Swapchain, []vk.Image, vk.SurfaceFormat, vk.Extent2D, error) {
+		// Swapchain support.
+		caps, fmts, modes := physicalDevice.SwapchainSupport(surface)
+
+		// Formats.
+		var format vk.SurfaceFormat
+		format, swapchain.FormatReason = ChooseSurfaceFormat(fmts,
+			swapchain.Options.SurfaceFormats,
+			swapchain.Options.ExtendedColorSpaces)
+
+		// Present Mode.
+		swapchain.PresentMode, swapchain.PresentModeReason = ChoosePresentMode(modes, swapchain.Options.PresentPolicy)
+		presentMode := swapchain.PresentMode
+
+		// Extent.
+		extent := func() vk.Extent2D {
+			if caps.CurrentExtent.Width != vk.MaxUint32 {
+				return caps.CurrentExtent
+			} else {
+				actualExtent := requestedExtent
+
+				actualExtent.Width = ClampUint32(actualExtent.Width,
+					caps.MinImageExtent.Width,
+					caps.MaxImageExtent.Width)
+				actualExtent.Height = ClampUint32(actualExtent.Height,
+					caps.MinImageExtent.Height,
+					caps.MaxImageExtent.Height)
+
+				return actualExtent
+			}
+		}()
+
+		// Image Count.
+		imgCount, err := ChooseImageCount(caps, swapchain.Options.ImageCount)
+		if err != nil {
+			return vk.Swapchain(vk.NullHandle), nil, format, extent, err
+		}
+
+		// Queue Families and Share mode.
+		qFamilyIndices, shareMode := func() ([]uint32, vk.SharingMode) {
+			gIdx, pIdx := physicalDevice.QueueFamilies(surface)
+			qfi := []uint32{gIdx.Val(), pIdx.Val()}
+			sm := vk.SharingModeConcurrent
+			if gIdx.Val() == pIdx.Val() {
+				sm = vk.SharingModeExclusive
+				qfi = qfi[:1]
+			}
+			return qfi, sm
+		}()
+
+		// Create the info object.
+		swapchainInfo := vk.SwapchainCreateInfo{
+			SType:                 vk.StructureTypeSwapchainCreateInfo,
+			Surface:               surface,
+			MinImageCount:         imgCount,
+			ImageFormat:           format.Format,
+			ImageColorSpace:       format.ColorSpace,
+			ImageExtent:           extent,
+			ImageArrayLayers:      1,
+			ImageUsage:            vk.ImageUsageFlags(vk.ImageUsageColorAttachmentBit),
+			ImageSharingMode:      shareMode,
+			QueueFamilyIndexCount: uint32(len(qFamilyIndices)),
+			PQueueFamilyIndices:   qFamilyIndices,
+			PreTransform:          caps.CurrentTransform,
+			CompositeAlpha:        vk.CompositeAlphaOpaqueBit,
+			PresentMode:           presentMode,
+			Clipped:               vk.True,
+			OldSwapchain:          oldSwapchain,
+		}
+
+		// Create the result object.
+		var handle vk.Swapchain
+
+		// Call the Vulkan function.
+		err = CheckResultInfo("vkCreateSwapchainKHR", vk.CreateSwapchain(device, &swapchainInfo, nil, &handle),
+			fmt.Sprintf("format=%d colorSpace=%d extent=%dx%d images=%d presentMode=%d",
+				format.Format, format.ColorSpace,
+				extent.Width, extent.Height,
+				imgCount, presentMode))
+		if err != nil {
+			return handle, nil, format, extent, err
+		}
+
+		// Fetch the Swapchain Images.
+		var count uint32
+		err = CheckResult("vkGetSwapchainImagesKHR", vk.GetSwapchainImages(device, handle, &count, nil))
+		if err != nil {
+			return handle, nil, format, extent, err
+		}
+		images := make([]vk.Image, count)
+		err = CheckResult("vkGetSwapchainImagesKHR", vk.GetSwapchainImages(device, handle, &count, images))
+
+		// return the swapchain and images.
+		return handle, images, format, extent, err
+	}
+
+	// Create the images to render into.
+	var format vk.SurfaceFormat
+	var err error
+	if swapchain.Offscreen() {
+		// Only formats that can be read back are available.
+		preferences := swapchain.Options.SurfaceFormats
+		if len(preferences) == 0 {
+			preferences = []vk.SurfaceFormat{HeadlessFormat}
+		}
+		format, swapchain.FormatReason = ChooseSurfaceFormat(offscreenSurfaceFormats(physicalDevice), preferences, false)
+
+		var img vk.Image
+		img, swapchain.offscreenAllocation, format, swapchain.Extent, err = newOffscreenImage(swapchain.allocator, physicalDevice, format, requestedExtent)
+		swapchain.Images = []vk.Image{img}
+	} else {
+		swapchain.Handle, swapchain.Images, format, swapchain.Extent, err = newSwapchain()
+	}
+	swapchain.ImageFormat = format.Format
+	swapchain.ColorSpace = format.ColorSpace
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Swapchain format: %s\n", swapchain.FormatReason)
+	if !swapchain.Offscreen() {
+		fmt.Printf("Swapchain present mode: %s\n", swapchain.PresentModeReason)
+	}
+
+	// Create the image views.
+	swapchain.ImageViews, err = func() ([]vk.ImageView, error) {
+		// Create the result object.
+		imageViews := make([]vk.ImageView, len(swapchain.Images))
+
+		// Create one image view per image.
+		for k, img := range swapchain.Images {
+			// Create the info object.
+			imageViewInfo := vk.ImageViewCreateInfo{
+				SType:    vk.StructureTypeImageViewCreateInfo,
+				Image:    img,
+				ViewType: vk.ImageViewType2d,
+				Format:   swapchain.ImageFormat,
+				Components: vk.ComponentMapping{
+					R: vk.ComponentSwizzleIdentity,
+					G: vk.ComponentSwizzleIdentity,
+					B: vk.ComponentSwizzleIdentity,
+					A: vk.ComponentSwizzleIdentity,
+				},
+				SubresourceRange: vk.ImageSubresourceRange{
+					AspectMask:     vk.ImageAspectFlags(vk.ImageAspectColorBit),
+					BaseMipLevel:   0,
+					LevelCount:     1,
+					BaseArrayLayer: 0,
+					LayerCount:     1,
+				},
+			}
+
+			// Call the Vulkan function.
+			err := CheckResultInfo("vkCreateImageView", vk.CreateImageView(device, &imageViewInfo, nil, &imageViews[k]),
+				fmt.Sprintf("image=%d format=%d", k, imageViewInfo.Format))
+			if err != nil {
+				return imageViews, err
+			}
+		}
+
+		// return the image views
+		return imageViews, nil
+	}()
+	return err
+}
@@ -0,0 +1,43 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestChooseImageCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  uint32
+		requested uint32
+		want      uint32
+		err       string
+	}{
+		{name: "default", min: 2, max: 8, requested: 0, want: 3},
+		{name: "default clamped to the maximum", min: 2, max: 2, requested: 0, want: 2},
+		{name: "default unbounded", min: 3, max: 0, requested: 0, want: 4},
+		{name: "requested", min: 2, max: 8, requested: 5, want: 5},
+		{name: "minimum", min: 2, max: 8, requested: 2, want: 2},
+		{name: "maximum", min: 2, max: 8, requested: 8, want: 8},
+		{name: "unbounded", min: 2, max: 0, requested: 100, want: 100},
+		{name: "below the minimum", min: 2, max: 8, requested: 1, err: "1 swapchain images requested, the surface needs at least 2"},
+		{name: "above the maximum", min: 2, max: 8, requested: 9, err: "9 swapchain images requested, the surface supports 2 to 8"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			caps := vk.SurfaceCapabilities{MinImageCount: test.min, MaxImageCount: test.max}
+			got, err := ChooseImageCount(caps, test.requested)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %d, %v", test.err, got, err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("got %d, %v, expected %d", got, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package renderer
+
+import (
+	"fmt"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// UniformRing hands out per-frame ranges of one persistently mapped, host
+// visible buffer, for uniforms that change every frame. Each frame in flight
+// has its own region, which is recycled once the frame's fence signals, so
+// writing never waits for the GPU. The buffer is bound with a dynamic
+// uniform buffer descriptor, see GraphicsPipelineDesc.DynamicUniformBuffers,
+// and the offsets the ring returns are passed to vkCmdBindDescriptorSets as
+// dynamic offsets.
+type UniformRing struct {
+	Buffer    *Buffer
+	FrameSize vk.DeviceSize // Bytes in each frame's region.
+	Alignment vk.DeviceSize // Of the offsets handed out.
+
+	app     *TriangleApplication
+	regions *RingRegions
+	memory  []byte
+}
+
+// RingRegions keeps track of the bytes used in the current frame's region
+// of a uniform ring. It only does the bookkeeping, so it works without a
+// device.
+type RingRegions struct {
+	FrameSize vk.DeviceSize // A multiple of Alignment.
+	Alignment vk.DeviceSize
+
+	frames uint
+	frame  uint
+	used   vk.DeviceSize
+}
+
+// Create the bookkeeping for frames regions of at least frameSize bytes,
+// starting with frame 0.
+func NewRingRegions(frameSize, alignment vk.DeviceSize, frames uint) *RingRegions {
+	if alignment == 0 {
+		alignment = 1
+	}
+	if frames == 0 {
+		frames = 1
+	}
+	return &RingRegions{
+		FrameSize: alignUp(frameSize, alignment),
+		Alignment: alignment,
+		frames:    frames,
+	}
+}
+
+// Frames is the number of regions.
+func (regions *RingRegions) Frames() uint {
+	return regions.frames
+}
+
+// Reserve size bytes at an aligned offset in the current frame's region.
+// The offset is from the start of the first region.
+func (regions *RingRegions) Reserve(size vk.DeviceSize) (vk.DeviceSize, error) {
+	offset := alignUp(regions.used, regions.Alignment)
+	if size == 0 || offset+size > regions.FrameSize {
+		return 0, fmt.Errorf("uniform ring: %d bytes don't fit, %d of the %d bytes for this frame are used",
+			size, regions.used, regions.FrameSize)
+	}
+	regions.used = offset + size
+	return offset + vk.DeviceSize(regions.frame)*regions.FrameSize, nil
+}
+
+// BeginFrame starts handing out the region for a frame in flight, which
+// wraps around the regions, and forgets what was reserved before.
+func (regions *RingRegions) BeginFrame(frame uint) {
+	regions.frame = frame % regions.frames
+	regions.used = 0
+}
+
+// NewUniformRing creates a ring with frameSize bytes for each frame in
+// flight. It has room for as many frames as there are swapchain images, the
+// most SetFramesInFlight allows, and is recycled by drawFrame until Cleanup.
+func NewUniformRing(app *TriangleApplication, frameSize vk.DeviceSize) (*UniformRing, error) {
+	if frameSize == 0 {
+		return nil, fmt.Errorf("uniform ring: frame size is 0")
+	}
+
+	// Offsets must suit both uniform and storage buffer descriptors.
+	limits := app.physicalDevice.Properties.Limits
+	alignment := vk.DeviceSize(1)
+	if limits.MinUniformBufferOffsetAlignment > alignment {
+		alignment = limits.MinUniformBufferOffsetAlignment
+	}
+	if limits.MinStorageBufferOffsetAlignment > alignment {
+		alignment = limits.MinStorageBufferOffsetAlignment
+	}
+
+	// Create the result object.
+	frames := app.FramesInFlight
+	if app.swapchain != nil && uint(len(app.swapchain.Images)) > frames {
+		frames = uint(len(app.swapchain.Images))
+	}
+	if frames == 0 {
+		frames = DefaultFramesInFlight
+	}
+	ring := &UniformRing{
+		app:     app,
+		regions: NewRingRegions(frameSize, alignment, frames),
+	}
+	ring.FrameSize = ring.regions.FrameSize
+	ring.Alignment = ring.regions.Alignment
+
+	// Create the buffer and keep it mapped.
+	var err error
+	ring.Buffer, err = NewBuffer(app.allocator,
+		ring.FrameSize*vk.DeviceSize(frames),
+		vk.BufferUsageFlags(vk.BufferUsageUniformBufferBit|vk.BufferUsageStorageBufferBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit|vk.MemoryPropertyHostCoherentBit),
+		vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit))
+	if err != nil {
+		return nil, err
+	}
+	ptr, err := app.allocator.Map(ring.Buffer.Allocation)
+	if err != nil {
+		ring.Buffer.Cleanup()
+		return nil, err
+	}
+	ring.memory = unsafe.Slice((*byte)(ptr), ring.Buffer.Size)
+
+	// Recycle it with the frames.
+	app.uniformRings = append(app.uniformRings, ring)
+	ring.beginFrame(app.currentFrame)
+	return ring, nil
+}
+
+// Frames is the number of frames in flight the ring has regions for.
+func (ring *UniformRing) Frames() uint {
+	return ring.regions.Frames()
+}
+
+// Alloc reserves size bytes in the current frame's region. It returns the
+// dynamic offset and the mapped bytes to fill. Allocate while recording,
+// such as from OnRecord; before then the GPU may still be reading the
+// region.
+func (ring *UniformRing) Alloc(size vk.DeviceSize) (uint32, []byte, error) {
+	offset, err := ring.regions.Reserve(size)
+	if err != nil {
+		return 0, nil, err
+	}
+	return uint32(offset), ring.memory[offset : offset+size], nil
+}
+
+// Write copies data into the current frame's region and returns its
+// dynamic offset.
+func (ring *UniformRing) Write(data []byte) (uint32, error) {
+	offset, memory, err := ring.Alloc(vk.DeviceSize(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	copy(memory, data)
+	return offset, nil
+}
+
+// WriteBlock encodes block with the layout into the current frame's region
+// and returns its dynamic offset.
+func (ring *UniformRing) WriteBlock(layout *BlockLayout, block interface{}) (uint32, error) {
+	data, err := layout.Encode(block)
+	if err != nil {
+		return 0, err
+	}
+	return ring.Write(data)
+}
+
+// DescriptorBufferInfo describes the ring for a dynamic descriptor of
+// blocks that are size bytes. The dynamic offset picks the block.
+func (ring *UniformRing) DescriptorBufferInfo(size vk.DeviceSize) vk.DescriptorBufferInfo {
+	return vk.DescriptorBufferInfo{
+		Buffer: ring.Buffer.Handle,
+		Offset: 0,
+		Range:  size,
+	}
+}
+
+// Start handing out the region of a frame whose fence has signaled.
+func (ring *UniformRing) beginFrame(frame uint) {
+	ring.regions.BeginFrame(frame)
+}
+
+// Destroy the buffer and stop recycling the ring. The GPU must be done with
+// it.
+func (ring *UniformRing) Cleanup() {
+	rings := ring.app.uniformRings
+	for k, v := range rings {
+		if v == ring {
+			ring.app.uniformRings = append(rings[:k], rings[k+1:]...)
+			break
+		}
+	}
+	if ring.Buffer != nil {
+		ring.Buffer.Cleanup()
+		ring.Buffer = nil
+	}
+	ring.memory = nil
+}
@@ -0,0 +1,128 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestNewRingRegions(t *testing.T) {
+	tests := []struct {
+		frameSize, alignment vk.DeviceSize
+		frames               uint
+		wantSize, wantAlign  vk.DeviceSize
+		wantFrames           uint
+	}{
+		{100, 64, 3, 128, 64, 3},
+		{128, 64, 2, 128, 64, 2},
+		{100, 0, 2, 100, 1, 2},
+		{1, 256, 0, 256, 256, 1},
+	}
+	for _, test := range tests {
+		regions := NewRingRegions(test.frameSize, test.alignment, test.frames)
+		if regions.FrameSize != test.wantSize || regions.Alignment != test.wantAlign || regions.Frames() != test.wantFrames {
+			t.Errorf("NewRingRegions(%d, %d, %d): frame size %d, alignment %d, %d frames, expected %d, %d, %d",
+				test.frameSize, test.alignment, test.frames,
+				regions.FrameSize, regions.Alignment, regions.Frames(),
+				test.wantSize, test.wantAlign, test.wantFrames)
+		}
+	}
+}
+
+func TestRingRegionsReserve(t *testing.T) {
+	// Each step starts a frame if frame is set, then reserves size bytes.
+	const none = ^uint(0)
+	type step struct {
+		frame  uint
+		size   vk.DeviceSize
+		offset vk.DeviceSize
+		err    string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "aligned offsets",
+			steps: []step{
+				{frame: none, size: 10, offset: 0},
+				{frame: none, size: 64, offset: 64},
+				{frame: none, size: 1, offset: 128},
+			},
+		},
+		{
+			name: "fills the region",
+			steps: []step{
+				{frame: none, size: 100, offset: 0},
+				{frame: none, size: 64, offset: 128},
+				{frame: none, size: 1, err: "1 bytes don't fit, 192 of the 192 bytes for this frame are used"},
+			},
+		},
+		{
+			name: "too big",
+			steps: []step{
+				{frame: none, size: 193, err: "193 bytes don't fit, 0 of the 192 bytes for this frame are used"},
+				{frame: none, size: 192, offset: 0},
+			},
+		},
+		{
+			name: "overflow after alignment",
+			steps: []step{
+				{frame: none, size: 129, offset: 0},
+				{frame: none, size: 1, err: "1 bytes don't fit, 129 of the 192 bytes for this frame are used"},
+			},
+		},
+		{
+			name: "zero bytes",
+			steps: []step{
+				{frame: none, size: 0, err: "0 bytes don't fit, 0 of the 192 bytes for this frame are used"},
+			},
+		},
+		{
+			name: "per frame offsets",
+			steps: []step{
+				{frame: 1, size: 8, offset: 192},
+				{frame: none, size: 8, offset: 256},
+				{frame: 2, size: 8, offset: 384},
+				{frame: 0, size: 8, offset: 0},
+			},
+		},
+		{
+			name: "new frame frees the region",
+			steps: []step{
+				{frame: 1, size: 192, offset: 192},
+				{frame: none, size: 1, err: "1 bytes don't fit"},
+				{frame: 1, size: 192, offset: 192},
+			},
+		},
+		{
+			name: "frames wrap",
+			steps: []step{
+				{frame: 3, size: 8, offset: 0},
+				{frame: 4, size: 8, offset: 192},
+				{frame: 8, size: 8, offset: 384},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regions := NewRingRegions(150, 64, 3)
+			for k, step := range test.steps {
+				if step.frame != none {
+					regions.BeginFrame(step.frame)
+				}
+				offset, err := regions.Reserve(step.size)
+				if step.err != "" {
+					if err == nil || !strings.Contains(err.Error(), step.err) {
+						t.Fatalf("step %d: expected an error containing %q, got %d, %v", k, step.err, offset, err)
+					}
+					continue
+				}
+				if err != nil || offset != step.offset {
+					t.Fatalf("step %d: got %d, %v, expected %d", k, offset, err, step.offset)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package renderer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// A vertex attribute format, described by the Go type that holds one
+// component.
+type vertexFormat struct {
+	Component  reflect.Kind // Invalid if Go has no matching type.
+	Count      uint32
+	Normalized bool // Integers read by the shader as floats in 0..1 or -1..1.
+}
+
+var vertexFormats = map[vk.Format]vertexFormat{
+	vk.FormatR8Unorm:            {reflect.Uint8, 1, true},
+	vk.FormatR8Snorm:            {reflect.Int8, 1, true},
+	vk.FormatR8Uint:             {reflect.Uint8, 1, false},
+	vk.FormatR8Sint:             {reflect.Int8, 1, false},
+	vk.FormatR8g8Unorm:          {reflect.Uint8, 2, true},
+	vk.FormatR8g8Snorm:          {reflect.Int8, 2, true},
+	vk.FormatR8g8Uint:           {reflect.Uint8, 2, false},
+	vk.FormatR8g8Sint:           {reflect.Int8, 2, false},
+	vk.FormatR8g8b8Unorm:        {reflect.Uint8, 3, true},
+	vk.FormatR8g8b8Snorm:        {reflect.Int8, 3, true},
+	vk.FormatR8g8b8Uint:         {reflect.Uint8, 3, false},
+	vk.FormatR8g8b8Sint:         {reflect.Int8, 3, false},
+	vk.FormatR8g8b8a8Unorm:      {reflect.Uint8, 4, true},
+	vk.FormatR8g8b8a8Snorm:      {reflect.Int8, 4, true},
+	vk.FormatR8g8b8a8Uint:       {reflect.Uint8, 4, false},
+	vk.FormatR8g8b8a8Sint:       {reflect.Int8, 4, false},
+	vk.FormatR16Unorm:           {reflect.Uint16, 1, true},
+	vk.FormatR16Snorm:           {reflect.Int16, 1, true},
+	vk.FormatR16Uint:            {reflect.Uint16, 1, false},
+	vk.FormatR16Sint:            {reflect.Int16, 1, false},
+	vk.FormatR16g16Unorm:        {reflect.Uint16, 2, true},
+	vk.FormatR16g16Snorm:        {reflect.Int16, 2, true},
+	vk.FormatR16g16Uint:         {reflect.Uint16, 2, false},
+	vk.FormatR16g16Sint:         {reflect.Int16, 2, false},
+	vk.FormatR16g16b16Unorm:     {reflect.Uint16, 3, true},
+	vk.FormatR16g16b16Snorm:     {reflect.Int16, 3, true},
+	vk.FormatR16g16b16Uint:      {reflect.Uint16, 3, false},
+	vk.FormatR16g16b16Sint:      {reflect.Int16, 3, false},
+	vk.FormatR16g16b16a16Unorm:  {reflect.Uint16, 4, true},
+	vk.FormatR16g16b16a16Snorm:  {reflect.Int16, 4, true},
+	vk.FormatR16g16b16a16Uint:   {reflect.Uint16, 4, false},
+	vk.FormatR16g16b16a16Sint:   {reflect.Int16, 4, false},
+	vk.FormatR16Sfloat:          {reflect.Invalid, 1, false},
+	vk.FormatR16g16Sfloat:       {reflect.Invalid, 2, false},
+	vk.FormatR16g16b16a16Sfloat: {reflect.Invalid, 4, false},
+	vk.FormatR32Uint:            {reflect.Uint32, 1, false},
+	vk.FormatR32Sint:            {reflect.Int32, 1, false},
+	vk.FormatR32Sfloat:          {reflect.Float32, 1, false},
+	vk.FormatR32g32Uint:         {reflect.Uint32, 2, false},
+	vk.FormatR32g32Sint:         {reflect.Int32, 2, false},
+	vk.FormatR32g32Sfloat:       {reflect.Float32, 2, false},
+	vk.FormatR32g32b32Uint:      {reflect.Uint32, 3, false},
+	vk.FormatR32g32b32Sint:      {reflect.Int32, 3, false},
+	vk.FormatR32g32b32Sfloat:    {reflect.Float32, 3, false},
+	vk.FormatR32g32b32a32Uint:   {reflect.Uint32, 4, false},
+	vk.FormatR32g32b32a32Sint:   {reflect.Int32, 4, false},
+	vk.FormatR32g32b32a32Sfloat: {reflect.Float32, 4, false},
+}
+
+// Name of a vertex format for messages, as used in pipeline files.
+func vertexFormatName(format vk.Format) string {
+	for name, v := range vertexFormatNames {
+		if vk.Format(v) == format {
+			return name
+		}
+	}
+	return fmt.Sprintf("format(%d)", format)
+}
+
+// The component type the shader reads the format as.
+func (f vertexFormat) shaderType() (kind TypeKind, signed bool) {
+	switch {
+	case f.Normalized, f.Component == reflect.Float32, f.Component == reflect.Invalid:
+		return TypeFloat, true
+	case f.Component == reflect.Int8, f.Component == reflect.Int16, f.Component == reflect.Int32:
+		return TypeInt, true
+	}
+	return TypeInt, false
+}
+
+func (f vertexFormat) String() string {
+	kind, signed := f.shaderType()
+	switch {
+	case kind == TypeFloat:
+		return "float"
+	case signed:
+		return "int"
+	}
+	return "uint"
+}
+
+// VertexLayout describes the fields of a vertex struct as a vertex input
+// binding and its attributes. vertex is a value or pointer of the struct
+// type. Fields are tagged with their shader location:
+//
+//	type Vertex struct {
+//		Pos   [3]float32 `vk:"location=0"`
+//		Color [4]uint8   `vk:"location=1,normalized"`
+//	}
+//
+// Components are float32, int8/16/32 or uint8/16/32, alone or in arrays of
+// up to 4. Integers are read as floats by the shader when normalized, and
+// "format=NAME" picks any format from the pipeline file names instead.
+// Untagged fields are padding. Three component 8 and 16 bit formats are
+// optional for devices, CheckVertexFormats reports when they are missing.
+func VertexLayout(vertex interface{}, binding uint32, inputRate vk.VertexInputRate) (vk.VertexInputBindingDescription, []vk.VertexInputAttributeDescription, error) {
+	t := reflect.TypeOf(vertex)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return vk.VertexInputBindingDescription{}, nil, fmt.Errorf("vertex layout: %T is not a struct", vertex)
+	}
+
+	// Create the result objects.
+	bindingDesc := vk.VertexInputBindingDescription{
+		Binding:   binding,
+		Stride:    uint32(t.Size()),
+		InputRate: inputRate,
+	}
+	attributes := make([]vk.VertexInputAttributeDescription, 0, t.NumField())
+
+	// One attribute per tagged field.
+	used := make(map[uint32]string)
+	for h := 0; h < t.NumField(); h++ {
+		field := t.Field(h)
+		tag, ok := field.Tag.Lookup("vk")
+		if !ok || tag == "-" {
+			continue
+		}
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("vertex layout: %s.%s: %s", t.Name(), field.Name, fmt.Sprintf(format, args...))
+		}
+
+		// Parse the tag.
+		location := -1
+		normalized := false
+		formatName := ""
+		for _, opt := range strings.Split(tag, ",") {
+			key, value := opt, ""
+			if k := strings.IndexByte(opt, '='); k >= 0 {
+				key, value = opt[:k], opt[k+1:]
+			}
+			switch strings.TrimSpace(key) {
+			case "location":
+				v, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return bindingDesc, nil, fail("bad location %q", value)
+				}
+				location = int(v)
+			case "normalized":
+				normalized = true
+			case "format":
+				formatName = value
+			default:
+				return bindingDesc, nil, fail("unknown tag option %q", opt)
+			}
+		}
+		if location < 0 {
+			return bindingDesc, nil, fail("tag %q has no location", tag)
+		}
+		if other, ok := used[uint32(location)]; ok {
+			return bindingDesc, nil, fail("location %d is also used by %s", location, other)
+		}
+		used[uint32(location)] = field.Name
+
+		// Pick the format.
+		var format vk.Format
+		if formatName != "" {
+			v, ok := vertexFormatNames[formatName]
+			if !ok {
+				return bindingDesc, nil, fail("unknown format %q", formatName)
+			}
+			format = vk.Format(v)
+		} else {
+			var err error
+			format, err = fieldVertexFormat(field.Type, normalized)
+			if err != nil {
+				return bindingDesc, nil, fail("%v", err)
+			}
+		}
+
+		attributes = append(attributes, vk.VertexInputAttributeDescription{
+			Location: uint32(location),
+			Binding:  binding,
+			Format:   format,
+			Offset:   uint32(field.Offset),
+		})
+	}
+	if len(attributes) == 0 {
+		return bindingDesc, nil, fmt.Errorf("vertex layout: %s has no fields tagged with a location", t.Name())
+	}
+	return bindingDesc, attributes, nil
+}
+
+// The format for a field's Go type.
+func fieldVertexFormat(t reflect.Type, normalized bool) (vk.Format, error) {
+	component, count := t, uint32(1)
+	if t.Kind() == reflect.Array {
+		component, count = t.Elem(), uint32(t.Len())
+	}
+	switch component.Kind() {
+	case reflect.Float32:
+		if normalized {
+			return vk.FormatUndefined, fmt.Errorf("%s is already a float, it can't be normalized", t)
+		}
+	case reflect.Uint32, reflect.Int32:
+		if normalized {
+			return vk.FormatUndefined, fmt.Errorf("32 bit integers can't be normalized")
+		}
+	}
+	for format, f := range vertexFormats {
+		if f.Component == component.Kind() && f.Count == count && f.Normalized == normalized {
+			return format, nil
+		}
+	}
+	return vk.FormatUndefined, fmt.Errorf("no vertex format for %s, use 1 to 4 float32, int8/16/32 or uint8/16/32 components", t)
+}
+
+// CheckVertexFormats reports the first vertex attribute whose format the
+// device can't read from a vertex buffer.
+func (phyDev PhysicalDevice) CheckVertexFormats(attributes []vk.VertexInputAttributeDescription) error {
+	for _, a := range attributes {
+		var formatProps vk.FormatProperties
+		vk.GetPhysicalDeviceFormatProperties(phyDev.Handle, a.Format, &formatProps)
+		formatProps.Deref()
+
+		if formatProps.BufferFeatures&vk.FormatFeatureFlags(vk.FormatFeatureVertexBufferBit) == 0 {
+			return fmt.Errorf("location %d: the device can't read vertex attributes with format %s",
+				a.Location, vertexFormatName(a.Format))
+		}
+	}
+	return nil
+}
+
+// CheckVertexInput compares the vertex shader's inputs with the pipeline's
+// vertex attributes. Every input needs an attribute at its location that the
+// shader reads as the same type of number, floats, signed or unsigned
+// integers. The component counts may differ, missing components are filled
+// in. Attributes the shader doesn't read are allowed.
+func CheckVertexInput(entryPoint EntryPoint, bindings []vk.VertexInputBindingDescription, attributes []vk.VertexInputAttributeDescription) error {
+	// Index the attributes by location.
+	byLocation := make(map[uint32]vk.VertexInputAttributeDescription)
+	for _, a := range attributes {
+		declared := false
+		for _, b := range bindings {
+			declared = declared || b.Binding == a.Binding
+		}
+		if !declared {
+			return fmt.Errorf("vertex attribute at location %d uses binding %d, which is not declared", a.Location, a.Binding)
+		}
+		byLocation[a.Location] = a
+	}
+
+	// Check the inputs in location order, so errors are repeatable.
+	inputs := make([]*Variable, 0, len(entryPoint.Inputs))
+	for _, input := range entryPoint.Inputs {
+		if !input.IsBuiltIn() && input.Decorations.Location.IsSet() {
+			inputs = append(inputs, input)
+		}
+	}
+	sort.Slice(inputs, func(a, b int) bool {
+		return inputs[a].Decorations.Location.Val() < inputs[b].Decorations.Location.Val()
+	})
+	for _, input := range inputs {
+		// Matrices take one location per column.
+		location := input.Decorations.Location.Val()
+		columns, column := uint32(1), input.Type
+		if input.Type.Kind == TypeMatrix {
+			columns, column = input.Type.Count, input.Type.Elem
+		}
+		component := column
+		if column.Kind == TypeVector {
+			component = column.Elem
+		}
+
+		for h := uint32(0); h < columns; h++ {
+			a, ok := byLocation[location+h]
+			if !ok {
+				return fmt.Errorf("location %d: vertex input %s %s has no vertex attribute", location+h, input.Type, input.Name)
+			}
+			f, ok := vertexFormats[a.Format]
+			if !ok || component.Width == 64 {
+				continue
+			}
+			kind, signed := f.shaderType()
+			if kind != component.Kind || (kind == TypeInt && signed != component.Signed) {
+				return fmt.Errorf("location %d: vertex input %s %s is read from a %s attribute with format %s",
+					location+h, input.Type, input.Name, f, vertexFormatName(a.Format))
+			}
+		}
+	}
+	return nil
+}
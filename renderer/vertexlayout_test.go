@@ -0,0 +1,206 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestVertexLayout(t *testing.T) {
+	type attribute struct {
+		Location uint32
+		Format   vk.Format
+		Offset   uint32
+	}
+	tests := []struct {
+		name       string
+		vertex     interface{}
+		stride     uint32
+		attributes []attribute
+	}{
+		{
+			name: "position and color",
+			vertex: struct {
+				Pos   [2]float32 `vk:"location=0"`
+				Color [4]uint8   `vk:"location=1,normalized"`
+			}{},
+			stride: 12,
+			attributes: []attribute{
+				{0, vk.FormatR32g32Sfloat, 0},
+				{1, vk.FormatR8g8b8a8Unorm, 8},
+			},
+		},
+		{
+			name: "three component integers",
+			vertex: struct {
+				Color  [3]uint8 `vk:"location=2,normalized"`
+				Weight float32  `vk:"location=0"`
+				Bone   [3]int16 `vk:"location=1"`
+				Normal [3]int8  `vk:"location=3,normalized"`
+			}{},
+			stride: 20,
+			attributes: []attribute{
+				{2, vk.FormatR8g8b8Unorm, 0},
+				{0, vk.FormatR32Sfloat, 4},
+				{1, vk.FormatR16g16b16Sint, 8},
+				{3, vk.FormatR8g8b8Snorm, 14},
+			},
+		},
+		{
+			name: "scalars",
+			vertex: struct {
+				ID    uint32 `vk:"location=0"`
+				Delta int32  `vk:"location=1"`
+				Layer uint16 `vk:"location=2"`
+				Bias  int8   `vk:"location=3,normalized"`
+			}{},
+			stride: 12,
+			attributes: []attribute{
+				{0, vk.FormatR32Uint, 0},
+				{1, vk.FormatR32Sint, 4},
+				{2, vk.FormatR16Uint, 8},
+				{3, vk.FormatR8Snorm, 10},
+			},
+		},
+		{
+			name: "padding and format override",
+			vertex: &struct {
+				Pos  [3]float32 `vk:"location=0"`
+				_    float32
+				Skip [4]float32 `vk:"-"`
+				UV   [2]uint16  `vk:"location=1,format=R16G16_SFLOAT"`
+			}{},
+			stride: 36,
+			attributes: []attribute{
+				{0, vk.FormatR32g32b32Sfloat, 0},
+				{1, vk.FormatR16g16Sfloat, 32},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binding, attributes, err := VertexLayout(test.vertex, 3, vk.VertexInputRateInstance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantBinding := vk.VertexInputBindingDescription{Binding: 3, Stride: test.stride, InputRate: vk.VertexInputRateInstance}
+			if binding != wantBinding {
+				t.Errorf("binding %+v, expected %+v", binding, wantBinding)
+			}
+			got := make([]attribute, len(attributes))
+			for k, a := range attributes {
+				if a.Binding != 3 {
+					t.Errorf("attribute %d has binding %d, expected 3", k, a.Binding)
+				}
+				got[k] = attribute{a.Location, a.Format, a.Offset}
+			}
+			if !reflect.DeepEqual(got, test.attributes) {
+				t.Errorf("attributes %+v, expected %+v", got, test.attributes)
+			}
+		})
+	}
+}
+
+func TestVertexLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		vertex interface{}
+		want   string
+	}{
+		{
+			name:   "not a struct",
+			vertex: [3]float32{},
+			want:   "[3]float32 is not a struct",
+		},
+		{
+			name: "no location",
+			vertex: struct {
+				Pos [3]float32 `vk:"normalized"`
+			}{},
+			want: `Pos: tag "normalized" has no location`,
+		},
+		{
+			name: "bad location",
+			vertex: struct {
+				Pos [3]float32 `vk:"location=x"`
+			}{},
+			want: `Pos: bad location "x"`,
+		},
+		{
+			name: "duplicate location",
+			vertex: struct {
+				Pos   [3]float32 `vk:"location=0"`
+				Color [3]float32 `vk:"location=0"`
+			}{},
+			want: "Color: location 0 is also used by Pos",
+		},
+		{
+			name: "unknown option",
+			vertex: struct {
+				Pos [3]float32 `vk:"location=0,packed"`
+			}{},
+			want: `Pos: unknown tag option "packed"`,
+		},
+		{
+			name: "normalized float",
+			vertex: struct {
+				Pos [3]float32 `vk:"location=0,normalized"`
+			}{},
+			want: "Pos: [3]float32 is already a float, it can't be normalized",
+		},
+		{
+			name: "normalized 32 bit integer",
+			vertex: struct {
+				ID uint32 `vk:"location=0,normalized"`
+			}{},
+			want: "ID: 32 bit integers can't be normalized",
+		},
+		{
+			name: "too many components",
+			vertex: struct {
+				Weights [5]float32 `vk:"location=0"`
+			}{},
+			want: "Weights: no vertex format for [5]float32",
+		},
+		{
+			name: "unsupported component",
+			vertex: struct {
+				Pos [2]float64 `vk:"location=0"`
+			}{},
+			want: "Pos: no vertex format for [2]float64",
+		},
+		{
+			name: "unknown format",
+			vertex: struct {
+				Pos [2]float32 `vk:"location=0,format=R32G32_FLOAT"`
+			}{},
+			want: `Pos: unknown format "R32G32_FLOAT"`,
+		},
+		{
+			name: "no tagged fields",
+			vertex: struct {
+				Pos [2]float32
+			}{},
+			want: "has no fields tagged with a location",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := VertexLayout(test.vertex, 0, vk.VertexInputRateVertex)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestVertexFormatNames(t *testing.T) {
+	for format := range vertexFormats {
+		name := vertexFormatName(format)
+		if v, ok := vertexFormatNames[name]; !ok || vk.Format(v) != format {
+			t.Errorf("format %d has no pipeline file name, got %s", format, name)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pipeline files can be written in the subset of YAML that covers what JSON
+// can say: block mappings and sequences, flow mappings and sequences on one
+// line, plain, single quoted and double quoted scalars, and comments.
+// Anchors, tags, block scalars and multiple documents are not supported.
+// Documents decode to the same values as encoding/json with UseNumber, so
+// the pipeline file reader handles both formats the same way.
+
+// A line of a YAML document, without its indentation and comment.
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+func yamlErrorf(line yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", line.number, fmt.Sprintf(format, args...))
+}
+
+// Plain scalars that are numbers.
+var yamlNumber = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+
+// decodeYAML decodes a document into maps, slices, strings, bools,
+// json.Numbers and nils.
+func decodeYAML(data []byte) (interface{}, error) {
+	// Split the lines, dropping blank lines and comments.
+	lines := make([]yamlLine, 0)
+	for k, text := range strings.Split(string(data), "\n") {
+		line := yamlLine{number: k + 1}
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+		line.text = strings.TrimLeft(text, " ")
+		line.indent = len(text) - len(line.text)
+		switch {
+		case line.text == "":
+			continue
+		case line.text[0] == '\t':
+			return nil, yamlErrorf(line, "tabs can't be used for indentation")
+		case text == "---" && len(lines) == 0:
+			continue
+		case text == "---" || text == "...":
+			return nil, yamlErrorf(line, "only one document is supported")
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	// Decode the top level block. Lines left over are indented less than
+	// the first one.
+	dec := &yamlDecoder{lines: lines}
+	doc, err := dec.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if dec.pos < len(lines) {
+		return nil, yamlErrorf(lines[dec.pos], "unexpected indentation")
+	}
+	return doc, nil
+}
+
+// Remove a comment, a # at the start of the line or after a space that is
+// not in a quoted scalar.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for k := 0; k < len(text); k++ {
+		c := text[k]
+		switch {
+		case quote == '"' && c == '\\':
+			k++
+		case quote == '\'' && c == '\'' && k+1 < len(text) && text[k+1] == '\'':
+			k++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case (c == '"' || c == '\'') && (k == 0 || strings.IndexByte(" \t[{,:", text[k-1]) >= 0):
+			quote = c
+		case c == '#' && (k == 0 || text[k-1] == ' ' || text[k-1] == '\t'):
+			return text[:k]
+		}
+	}
+	return text
+}
+
+// Reports if a line is a block sequence item.
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// The index of the colon ending the key of a block mapping entry, or -1 if
+// the text isn't one.
+func yamlKeyEnd(text string) int {
+	start := 0
+	switch text[0] {
+	case '{', '[':
+		return -1
+	case '"', '\'':
+		_, n, err := yamlQuoted(text)
+		if err != nil {
+			return -1
+		}
+		start = n
+	}
+	for k := start; k < len(text); k++ {
+		if text[k] == ':' && (k+1 == len(text) || text[k+1] == ' ') {
+			return k
+		}
+	}
+	return -1
+}
+
+// Decode a quoted scalar at the start of the text, returning its value and
+// the number of bytes it used.
+func yamlQuoted(text string) (string, int, error) {
+	quote := text[0]
+	for k := 1; k < len(text); k++ {
+		switch {
+		case quote == '"' && text[k] == '\\':
+			k++
+		case quote == '\'' && text[k] == '\'' && k+1 < len(text) && text[k+1] == '\'':
+			k++
+		case text[k] == quote && quote == '"':
+			s, err := strconv.Unquote(text[:k+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("bad double quoted scalar %s", text[:k+1])
+			}
+			return s, k + 1, nil
+		case text[k] == quote:
+			return strings.ReplaceAll(text[1:k], "''", "'"), k + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted scalar %s", text)
+}
+
+// Resolve a plain scalar to null, a bool, a number or a string.
+func yamlPlain(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlNumber.MatchString(text) {
+		return json.Number(strings.TrimPrefix(text, "+"))
+	}
+	return text
+}
+
+// Decode the value after a key or sequence dash: a flow collection or a
+// scalar.
+func yamlValue(line yamlLine, text string) (interface{}, error) {
+	switch text[0] {
+	case '{', '[':
+		flow := &yamlFlow{line: line, text: text}
+		v, err := flow.value()
+		if err != nil {
+			return nil, err
+		}
+		flow.skipSpaces()
+		if flow.pos < len(text) {
+			return nil, yamlErrorf(line, "unexpected %q after the flow collection", text[flow.pos:])
+		}
+		return v, nil
+	case '"', '\'':
+		s, n, err := yamlQuoted(text)
+		if err != nil {
+			return nil, yamlErrorf(line, "%v", err)
+		}
+		if n != len(text) {
+			return nil, yamlErrorf(line, "unexpected %q after the quoted scalar", text[n:])
+		}
+		return s, nil
+	case '&', '*', '!', '|', '>':
+		return nil, yamlErrorf(line, "anchors, aliases, tags and block scalars are not supported")
+	}
+	return yamlPlain(text), nil
+}
+
+// Block structure decoder.
+type yamlDecoder struct {
+	lines []yamlLine
+	pos   int
+}
+
+// Decode the block starting at the current line, which is at indent.
+func (dec *yamlDecoder) block(indent int) (interface{}, error) {
+	line := dec.lines[dec.pos]
+	switch {
+	case isYAMLSequenceItem(line.text):
+		return dec.sequence(indent)
+	case yamlKeyEnd(line.text) >= 0:
+		return dec.mapping(indent)
+	}
+	dec.pos++
+	return yamlValue(line, line.text)
+}
+
+// Decode the entries of a block mapping at indent.
+func (dec *yamlDecoder) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for dec.pos < len(dec.lines) {
+		line := dec.lines[dec.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlErrorf(line, "unexpected indentation")
+		}
+		end := yamlKeyEnd(line.text)
+		if end < 0 || isYAMLSequenceItem(line.text) {
+			return nil, yamlErrorf(line, "expected a key and a colon")
+		}
+
+		// The key.
+		key := strings.TrimSpace(line.text[:end])
+		if key == "" {
+			return nil, yamlErrorf(line, "expected a key and a colon")
+		}
+		if key[0] == '"' || key[0] == '\'' {
+			quoted, n, err := yamlQuoted(key)
+			if err != nil {
+				return nil, yamlErrorf(line, "%v", err)
+			}
+			if n != len(key) {
+				return nil, yamlErrorf(line, "unexpected %q after the quoted key", key[n:])
+			}
+			key = quoted
+		}
+		if _, ok := m[key]; ok {
+			return nil, yamlErrorf(line, "duplicate key %q", key)
+		}
+		dec.pos++
+
+		// The value, on the same line or in the block below. A sequence
+		// may be at the same indent as its key.
+		var value interface{}
+		var err error
+		rest := strings.TrimSpace(line.text[end+1:])
+		switch {
+		case rest != "":
+			value, err = yamlValue(line, rest)
+		case dec.pos == len(dec.lines):
+		case dec.lines[dec.pos].indent > indent:
+			value, err = dec.block(dec.lines[dec.pos].indent)
+		case dec.lines[dec.pos].indent == indent && isYAMLSequenceItem(dec.lines[dec.pos].text):
+			value, err = dec.sequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// Decode the items of a block sequence at indent.
+func (dec *yamlDecoder) sequence(indent int) (interface{}, error) {
+	s := make([]interface{}, 0)
+	for dec.pos < len(dec.lines) {
+		line := dec.lines[dec.pos]
+		if line.indent < indent || (line.indent == indent && !isYAMLSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, yamlErrorf(line, "unexpected indentation")
+		}
+
+		// The item is either in the block below the dash, or starts a
+		// block at the column after it, so the keys of a "- key: value"
+		// item continue on the lines below.
+		var value interface{}
+		var err error
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			dec.pos++
+			if dec.pos < len(dec.lines) && dec.lines[dec.pos].indent > indent {
+				value, err = dec.block(dec.lines[dec.pos].indent)
+			}
+		} else {
+			column := indent + len(line.text) - len(rest)
+			dec.lines[dec.pos] = yamlLine{number: line.number, indent: column, text: rest}
+			value, err = dec.block(column)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+	}
+	return s, nil
+}
+
+// Flow collection decoder, for one line.
+type yamlFlow struct {
+	line yamlLine
+	text string
+	pos  int
+}
+
+func (flow *yamlFlow) skipSpaces() {
+	for flow.pos < len(flow.text) && flow.text[flow.pos] == ' ' {
+		flow.pos++
+	}
+}
+
+// Reports if the next character is c, and skips it if it is.
+func (flow *yamlFlow) next(c byte) bool {
+	flow.skipSpaces()
+	if flow.pos < len(flow.text) && flow.text[flow.pos] == c {
+		flow.pos++
+		return true
+	}
+	return false
+}
+
+// Decode a scalar ending at one of the stop characters. Plain scalars are
+// resolved unless they are keys.
+func (flow *yamlFlow) scalar(stops string, key bool) (interface{}, error) {
+	flow.skipSpaces()
+	rest := flow.text[flow.pos:]
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		s, n, err := yamlQuoted(rest)
+		if err != nil {
+			return nil, yamlErrorf(flow.line, "%v", err)
+		}
+		flow.pos += n
+		return s, nil
+	}
+	n := strings.IndexAny(rest, stops)
+	if n < 0 {
+		n = len(rest)
+	}
+	flow.pos += n
+	text := strings.TrimSpace(rest[:n])
+	if key {
+		return text, nil
+	}
+	return yamlPlain(text), nil
+}
+
+// Decode a flow mapping, sequence or scalar.
+func (flow *yamlFlow) value() (interface{}, error) {
+	// Flow mapping.
+	if flow.next('{') {
+		m := make(map[string]interface{})
+		if flow.next('}') {
+			return m, nil
+		}
+		for {
+			key, err := flow.scalar(":,}", true)
+			if err != nil {
+				return nil, err
+			}
+			if !flow.next(':') {
+				return nil, yamlErrorf(flow.line, "expected a colon after the key %q", key)
+			}
+			if _, ok := m[key.(string)]; ok {
+				return nil, yamlErrorf(flow.line, "duplicate key %q", key)
+			}
+			if m[key.(string)], err = flow.value(); err != nil {
+				return nil, err
+			}
+			if flow.next('}') {
+				return m, nil
+			}
+			if !flow.next(',') {
+				return nil, yamlErrorf(flow.line, "expected a comma or } in the flow mapping, flow collections must be on one line")
+			}
+		}
+	}
+
+	// Flow sequence.
+	if flow.next('[') {
+		s := make([]interface{}, 0)
+		if flow.next(']') {
+			return s, nil
+		}
+		for {
+			v, err := flow.value()
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			if flow.next(']') {
+				return s, nil
+			}
+			if !flow.next(',') {
+				return nil, yamlErrorf(flow.line, "expected a comma or ] in the flow sequence, flow collections must be on one line")
+			}
+		}
+	}
+
+	return flow.scalar(",]}", false)
+}
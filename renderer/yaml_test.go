@@ -0,0 +1,112 @@
+package renderer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	type m = map[string]interface{}
+	type s = []interface{}
+	n := func(v string) json.Number { return json.Number(v) }
+	tests := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"empty", "# nothing\n\n", nil},
+		{"scalar", "hello world", "hello world"},
+		{
+			name: "plain scalars",
+			yaml: "a: 1\nb: -2.5e3\nc: +.5\nd: true\ne: False\nf: ~\ng:\nh: null\ni: 1.0.0\nj: R32G32_SFLOAT",
+			want: m{"a": n("1"), "b": n("-2.5e3"), "c": n(".5"), "d": true, "e": false, "f": nil, "g": nil, "h": nil, "i": "1.0.0", "j": "R32G32_SFLOAT"},
+		},
+		{
+			name: "quoted scalars",
+			yaml: `a: "x: \"y\" # z\n"` + "\nb: 'it''s # here'\n'c d': \"1\"",
+			want: m{"a": "x: \"y\" # z\n", "b": "it's # here", "c d": "1"},
+		},
+		{
+			name: "comments",
+			yaml: "# header\na: 1 # one\nb: x#y\n  # indented comment\nc: 2",
+			want: m{"a": n("1"), "b": "x#y", "c": n("2")},
+		},
+		{
+			name: "nested mappings",
+			yaml: "---\na:\n  b:\n    c: 1\n  d: 2\ne: 3\n",
+			want: m{"a": m{"b": m{"c": n("1")}, "d": n("2")}, "e": n("3")},
+		},
+		{
+			name: "sequences",
+			yaml: "a:\n  - 1\n  -\n    - 2\n    - 3\n  - - 4\n    - 5\nb:\n- x\n- y\n",
+			want: m{"a": s{n("1"), s{n("2"), n("3")}, s{n("4"), n("5")}}, "b": s{"x", "y"}},
+		},
+		{
+			name: "mappings in sequences",
+			yaml: "- name: a\n  value: 1\n- name: b\n  nested:\n    - x: 1\n      y: 2\n-\n  name: c\n",
+			want: s{
+				m{"name": "a", "value": n("1")},
+				m{"name": "b", "nested": s{m{"x": n("1"), "y": n("2")}}},
+				m{"name": "c"},
+			},
+		},
+		{
+			name: "flow collections",
+			yaml: `a: {b: 1, "c": [x, 'y', {}], d: , e: []}` + "\nf: [ {g: true} ]",
+			want: m{"a": m{"b": n("1"), "c": s{"x", "y", m{}}, "d": nil, "e": s{}}, "f": s{m{"g": true}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeYAML([]byte(test.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("decoded %#v, expected %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"tab indentation", "a:\n\tb: 1", "yaml: line 2: tabs can't be used for indentation"},
+		{"second document", "a: 1\n---\nb: 2", "yaml: line 2: only one document is supported"},
+		{"document end", "a: 1\n...", "yaml: line 2: only one document is supported"},
+		{"duplicate key", "a: 1\nb: 2\na: 3", `yaml: line 3: duplicate key "a"`},
+		{"duplicate flow key", "a: {b: 1, b: 2}", `yaml: line 1: duplicate key "b"`},
+		{"deeper entry", "a: 1\n  b: 2", "yaml: line 2: unexpected indentation"},
+		{"shallower entry", "  a: 1\nb: 2", "yaml: line 2: unexpected indentation"},
+		{"item in a mapping", "a: 1\n- b", "yaml: line 2: expected a key and a colon"},
+		{"scalar in a mapping", "a: 1\nb", "yaml: line 2: expected a key and a colon"},
+		{"empty key", ": x", "yaml: line 1: expected a key and a colon"},
+		{"empty nested key", "a:\n  : 1", "yaml: line 2: expected a key and a colon"},
+		{"empty key in an item", "- : 1", "yaml: line 1: expected a key and a colon"},
+		{"text after a quoted key", `"a" junk: 1`, `yaml: line 1: unexpected " junk" after the quoted key`},
+		{"deeper item", "- a\n   - b", "yaml: line 2: unexpected indentation"},
+		{"anchor", "a: &x 1", "yaml: line 1: anchors, aliases, tags and block scalars are not supported"},
+		{"block scalar", "a: |\n  text", "yaml: line 1: anchors, aliases, tags and block scalars are not supported"},
+		{"unterminated quote", `a: "b`, `yaml: line 1: unterminated quoted scalar "b`},
+		{"bad escape", `a: "\q"`, `yaml: line 1: bad double quoted scalar "\q"`},
+		{"text after quote", `a: "b" c`, `yaml: line 1: unexpected " c" after the quoted scalar`},
+		{"multi line flow", "a: [1,\n  2]", "yaml: line 1: expected a comma or ] in the flow sequence, flow collections must be on one line"},
+		{"unclosed flow mapping", "a: {b: 1", "yaml: line 1: expected a comma or } in the flow mapping, flow collections must be on one line"},
+		{"flow key without colon", "a: {b}", `yaml: line 1: expected a colon after the key "b"`},
+		{"text after flow", "a: [1] 2", `yaml: line 1: unexpected "2" after the flow collection`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeYAML([]byte(test.yaml))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+#version 450
+
+layout(set = 0, binding = 0) uniform Frame {
+    mat4 transform;
+    float time;
+} frame;
+
+layout(location = 0) in vec2 inPosition;
+layout(location = 1) in vec3 inColor;
+
+layout(location = 0) out vec3 fragColor;
+
+void main() {
+    gl_Position = frame.transform * vec4(inPosition, 0.0, 1.0);
+    fragColor = inColor * (0.75 + 0.25 * sin(frame.time * 3.0));
+}